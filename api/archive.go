@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/json"
+	"github.com/alice-ws/alice/board"
+	"github.com/julienschmidt/httprouter"
+	"github.com/spf13/viper"
+	"log"
+	"net/http"
+)
+
+func getArchivedThreadsHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	store, ok := storeFor(w, ps)
+	if !ok {
+		return
+	}
+	t, err := store.GetArchivedThreads()
+
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		_ = json.NewEncoder(w).Encode(boardResponse{Status: "FAILURE"})
+		return
+	}
+	if !moderating(r) {
+		for i := range t {
+			t[i] = t[i].WithoutIPHashes()
+		}
+	}
+
+	addHeaders(w)
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(t)
+}
+
+func getArchivedThreadHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	store, ok := storeFor(w, ps)
+	if !ok {
+		return
+	}
+
+	threadNo := r.URL.Query().Get("no")
+	if threadNo == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(boardResponse{Status: "FAILURE"})
+		return
+	}
+	t, err := store.GetArchivedThread(threadNo)
+
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		_ = json.NewEncoder(w).Encode(boardResponse{Status: "FAILURE"})
+		return
+	}
+	if !moderating(r) {
+		t = t.WithoutIPHashes()
+	}
+
+	addHeaders(w)
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(boardResponse{Status: "SUCCESS", No: threadNo, Thread: t, Type: Thread})
+}
+
+// Prunes the board, removing the media of deleted threads and moving the media of archived threads
+// to the archive media group if one is configured.
+func prune(store *board.Store) {
+	pruned, err := store.Prune()
+	if err != nil {
+		log.Printf("Error pruning board %s: %v", store.ID, err)
+	}
+
+	archiveGroup := viper.GetString("archive.media.group")
+	for _, t := range pruned {
+		if !store.Settings().Archive {
+			removeMedia(t.Posts()...)
+			continue
+		}
+		if archiveGroup == "" {
+			continue
+		}
+		// Media shared with posts outside the thread, on a board or in an archive, is left where it is
+		// as those posts still point at it.
+		own := make(map[string]int64)
+		for _, p := range t.Posts() {
+			for _, URI := range p.Media() {
+				own[URI]++
+			}
+		}
+		err := store.MoveArchivedMedia(t.Key(), func(URI string) (string, error) {
+			release, err := mediaLocks.Lock(URI, mediaLockTimeout)
+			if err != nil {
+				return URI, err
+			}
+			defer release()
+
+			if mediaReferences(URI) > own[URI] {
+				return URI, nil
+			}
+			return mediaRepo.Move(URI, archiveGroup)
+		})
+		if err != nil {
+			log.Printf("Error moving media of archived thread %d to %s: %v", t.No, archiveGroup, err)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+// Package auth issues and validates the tokens used to access moderation routes.
+package auth
+
+import (
+	"context"
+	"errors"
+	"github.com/dgrijalva/jwt-go"
+	"golang.org/x/crypto/bcrypt"
+	"time"
+)
+
+type Role string
+
+const (
+	Admin     Role = "admin"
+	Moderator Role = "moderator"
+	Janitor   Role = "janitor"
+)
+
+// Rank of each role. A role can do everything a lower ranked role can.
+var ranks = map[Role]int{
+	Janitor:   1,
+	Moderator: 2,
+	Admin:     3,
+}
+
+// Includes returns whether the role has at least the permissions of the other role.
+func (r Role) Includes(other Role) bool {
+	rank, ok := ranks[r]
+	return ok && rank >= ranks[other]
+}
+
+// User is a configured user with a bcrypt hashed password.
+type User struct {
+	Password string `json:"password"`
+	Role     Role   `json:"role"`
+}
+
+// Claims are stored in issued tokens and attached to authenticated requests.
+type Claims struct {
+	Role Role `json:"role"`
+	jwt.StandardClaims
+}
+
+// Username returns the user the token was issued to.
+func (c Claims) Username() string {
+	return c.Subject
+}
+
+type Authenticator struct {
+	key      []byte
+	users    map[string]User
+	validFor time.Duration
+}
+
+// ErrNoKey is returned by an authenticator without a signing key, which cannot log users in or accept tokens.
+var ErrNoKey = errors.New("no key is configured for signing tokens")
+
+// Compared against when a user does not exist, so that logging in takes the same time for unknown users.
+var unknownUserPassword, _ = bcrypt.GenerateFromPassword([]byte("unknown"), bcrypt.DefaultCost)
+
+func NewAuthenticator(key []byte, users map[string]User, validFor time.Duration) *Authenticator {
+	return &Authenticator{key: key, users: users, validFor: validFor}
+}
+
+// Login checks the user's password and returns a signed token for the user.
+func (a *Authenticator) Login(username, password string) (string, error) {
+	if len(a.key) == 0 {
+		return "", ErrNoKey
+	}
+	user, ok := a.users[username]
+	hash := []byte(user.Password)
+	if !ok {
+		hash = unknownUserPassword
+	}
+
+	if err := bcrypt.CompareHashAndPassword(hash, []byte(password)); err != nil || !ok {
+		return "", errors.New("invalid username or password")
+	}
+
+	now := time.Now()
+	claims := Claims{
+		Role: user.Role,
+		StandardClaims: jwt.StandardClaims{
+			Subject:   username,
+			IssuedAt:  now.Unix(),
+			ExpiresAt: now.Add(a.validFor).Unix(),
+		},
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(a.key)
+}
+
+// Validate checks the token was signed by the authenticator and has not expired, returning its claims.
+func (a *Authenticator) Validate(token string) (Claims, error) {
+	if len(a.key) == 0 {
+		return Claims{}, ErrNoKey
+	}
+	var claims Claims
+	_, err := jwt.ParseWithClaims(token, &claims, func(t *jwt.Token) (interface{}, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, errors.New("unexpected signing method " + t.Method.Alg())
+		}
+		return a.key, nil
+	})
+	if err != nil {
+		return Claims{}, errors.New("invalid token: " + err.Error())
+	}
+	if _, ok := ranks[claims.Role]; !ok {
+		return Claims{}, errors.New("invalid token: unknown role " + string(claims.Role))
+	}
+	return claims, nil
+}
+
+type contextKey struct{}
+
+// WithClaims returns a copy of the context carrying the claims of an authenticated user.
+func WithClaims(ctx context.Context, claims Claims) context.Context {
+	return context.WithValue(ctx, contextKey{}, claims)
+}
+
+// ClaimsFrom returns the claims attached to the context, if the request was authenticated.
+func ClaimsFrom(ctx context.Context) (Claims, bool) {
+	claims, ok := ctx.Value(contextKey{}).(Claims)
+	return claims, ok
+}
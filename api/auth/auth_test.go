@@ -0,0 +1,92 @@
+package auth
+
+import (
+	"github.com/dgrijalva/jwt-go"
+	"golang.org/x/crypto/bcrypt"
+	"testing"
+	"time"
+)
+
+func TestRole_Includes(t *testing.T) {
+	tests := []struct {
+		role  Role
+		other Role
+		want  bool
+	}{
+		{Admin, Moderator, true},
+		{Admin, Janitor, true},
+		{Moderator, Moderator, true},
+		{Moderator, Admin, false},
+		{Janitor, Moderator, false},
+		{Role("poster"), Janitor, false},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.role)+" includes "+string(tt.other), func(t *testing.T) {
+			if got := tt.role.Includes(tt.other); got != tt.want {
+				t.Errorf("Includes() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuthenticator_LoginAndValidate(t *testing.T) {
+	a := authenticator(time.Hour)
+
+	token, err := a.Login("alice", "password")
+	if err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+
+	claims, err := a.Validate(token)
+	if err != nil || claims.Username() != "alice" || claims.Role != Moderator {
+		t.Errorf("Validate() = %v, %v, want alice as moderator", claims, err)
+	}
+}
+
+func TestAuthenticator_Login_rejectsInvalidCredentials(t *testing.T) {
+	a := authenticator(time.Hour)
+
+	if _, err := a.Login("alice", "wrong"); err == nil {
+		t.Errorf("expected error for wrong password")
+	}
+	if _, err := a.Login("bob", "password"); err == nil {
+		t.Errorf("expected error for unknown user")
+	}
+}
+
+func TestAuthenticator_Validate_rejectsInvalidTokens(t *testing.T) {
+	a := authenticator(time.Hour)
+	expired, _ := authenticator(-time.Hour).Login("alice", "password")
+	otherKey, _ := NewAuthenticator([]byte("other"), a.users, time.Hour).Login("alice", "password")
+	unsigned, _ := jwt.NewWithClaims(jwt.SigningMethodNone, Claims{Role: Admin}).SignedString(jwt.UnsafeAllowNoneSignatureType)
+
+	for name, token := range map[string]string{"expired": expired, "other key": otherKey, "unsigned": unsigned, "empty": ""} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := a.Validate(token); err == nil {
+				t.Errorf("Validate() error = nil, want error")
+			}
+		})
+	}
+}
+
+func TestAuthenticator_withoutKey(t *testing.T) {
+	a := authenticator(time.Hour)
+	token, _ := a.Login("alice", "password")
+	unkeyed := NewAuthenticator(nil, a.users, time.Hour)
+	forged, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, Claims{Role: Admin}).SignedString([]byte{})
+
+	if _, err := unkeyed.Login("alice", "password"); err != ErrNoKey {
+		t.Errorf("Login() error = %v, want %v", err, ErrNoKey)
+	}
+	for _, token := range []string{token, forged} {
+		if _, err := unkeyed.Validate(token); err != ErrNoKey {
+			t.Errorf("Validate() error = %v, want %v", err, ErrNoKey)
+		}
+	}
+}
+
+func authenticator(validFor time.Duration) *Authenticator {
+	hash, _ := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+	users := map[string]User{"alice": {Password: string(hash), Role: Moderator}}
+	return NewAuthenticator([]byte("key"), users, validFor)
+}
@@ -0,0 +1,170 @@
+// Package ban keeps the bans moderators place on the IP addresses of abusive posters.
+package ban
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/alice-ws/alice/data"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Ban stops posters in a range of IP addresses from posting, on one board or every board, until it expires.
+type Ban struct {
+	ID string `json:"id"`
+	// Range is the banned addresses in CIDR notation. A single address is a range of one.
+	Range string `json:"range"`
+	// Board is the ID of the board the ban applies to, or empty for every board.
+	Board   string    `json:"board"`
+	Reason  string    `json:"reason"`
+	Created time.Time `json:"created"`
+	// Expires is when the ban is lifted, or zero for a permanent ban.
+	Expires time.Time `json:"expires"`
+}
+
+func (b Ban) String() string {
+	bytes, _ := json.Marshal(b)
+	return string(bytes)
+}
+
+func newBanFrom(mjson string) (Ban, error) {
+	var b Ban
+	err := json.Unmarshal([]byte(mjson), &b)
+	if err != nil {
+		return Ban{}, errors.New("cannot parse json" + err.Error())
+	}
+	return b, nil
+}
+
+// Returns whether the ban has expired by the given time.
+func (b Ban) expired(now time.Time) bool {
+	return !b.Expires.IsZero() && !now.Before(b.Expires)
+}
+
+// Returns whether the ban stops the IP address posting on the board.
+func (b Ban) covers(ip net.IP, boardID string) bool {
+	if b.Board != "" && b.Board != boardID {
+		return false
+	}
+	_, network, err := net.ParseCIDR(b.Range)
+	return err == nil && network.Contains(ip)
+}
+
+// ParseRange returns the IP address or CIDR range in CIDR notation.
+func ParseRange(s string) (string, error) {
+	if strings.Contains(s, "/") {
+		_, network, err := net.ParseCIDR(s)
+		if err != nil {
+			return "", errors.New("invalid range " + s)
+		}
+		return network.String(), nil
+	}
+
+	ip := net.ParseIP(s)
+	if ip == nil {
+		return "", errors.New("invalid IP address " + s)
+	}
+	if ip.To4() != nil {
+		return ip.String() + "/32", nil
+	}
+	return ip.String() + "/128", nil
+}
+
+// Store keeps bans in the DB.
+type Store struct {
+	db   data.KeyValueDB
+	bans data.OrderedDB
+}
+
+func NewStore(db data.KeyValueDB, bans data.OrderedDB) *Store {
+	return &Store{db: db, bans: bans}
+}
+
+// Key of the ordered set of ban IDs, scored by when they were created
+const bansKey = "bans"
+
+// Key of the number of bans ever created, which numbers new bans
+const banCountKey = "bans:no"
+
+// Returns key for a ban that is stored in the DB
+func banKey(ID string) string {
+	return "ban:" + ID
+}
+
+// Add stores the ban, returning it with its ID and creation time.
+func (s *Store) Add(b Ban) (Ban, error) {
+	var err error
+	b.Range, err = ParseRange(b.Range)
+	if err != nil {
+		return Ban{}, err
+	}
+
+	count, err := s.db.Increment(banCountKey)
+	if err != nil {
+		return Ban{}, err
+	}
+	b.ID = strconv.FormatInt(count, 10)
+	b.Created = time.Now()
+
+	err = s.db.Set(data.NewKeyValuePair(banKey(b.ID), b.String()))
+	if err != nil {
+		return Ban{}, err
+	}
+	return b, s.bans.SetOrdered(data.NewKeyValuePair(bansKey, b.ID), int(b.Created.UnixNano()))
+}
+
+// Lift removes the ban with the ID.
+func (s *Store) Lift(ID string) error {
+	if _, err := s.db.Get(banKey(ID)); err != nil {
+		return errors.New("no such ban " + ID)
+	}
+	err := s.db.Remove(banKey(ID))
+	if err != nil {
+		return err
+	}
+	return s.bans.RemoveOrdered(data.NewKeyValuePair(bansKey, ID))
+}
+
+// List returns the bans that have not expired, newest first. Expired bans are removed.
+func (s *Store) List() ([]Ban, error) {
+	now := time.Now()
+	bans := make([]Ban, 0)
+	for _, ID := range s.bans.GetAllOrderedByScore(bansKey) {
+		banString, err := s.db.Get(banKey(ID))
+		if err != nil {
+			// Lifted since the IDs were read
+			continue
+		}
+		b, err := newBanFrom(banString)
+		if err != nil {
+			return nil, errors.New("error getting bans")
+		}
+		if b.expired(now) {
+			_ = s.Lift(ID)
+			continue
+		}
+		bans = append(bans, b)
+	}
+	return bans, nil
+}
+
+// Find returns a ban stopping the IP address posting on the board, if there is one.
+func (s *Store) Find(ip string, boardID string) (Ban, bool, error) {
+	address := net.ParseIP(ip)
+	if address == nil {
+		return Ban{}, false, errors.New("invalid IP address " + ip)
+	}
+
+	bans, err := s.List()
+	if err != nil {
+		return Ban{}, false, err
+	}
+	for _, b := range bans {
+		if b.covers(address, boardID) {
+			return b, true, nil
+		}
+	}
+	return Ban{}, false, nil
+}
@@ -0,0 +1,92 @@
+package ban
+
+import (
+	"github.com/alice-ws/alice/data"
+	"testing"
+	"time"
+)
+
+func TestParseRange(t *testing.T) {
+	tests := []struct {
+		in, want string
+		wantErr  bool
+	}{
+		{in: "1.2.3.4", want: "1.2.3.4/32"},
+		{in: "1.2.3.4/16", want: "1.2.0.0/16"},
+		{in: "2001:db8::1", want: "2001:db8::1/128"},
+		{in: "2001:db8::/32", want: "2001:db8::/32"},
+		{in: "", wantErr: true},
+		{in: "1.2.3", wantErr: true},
+		{in: "1.2.3.4/33", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseRange(tt.in)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("ParseRange() = %s, %v, want %s", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestStore_Find(t *testing.T) {
+	db := data.NewMemoryDB()
+	store := NewStore(db, db)
+	_, _ = store.Add(Ban{Range: "1.2.3.4", Reason: "spam"})
+	_, _ = store.Add(Ban{Range: "10.0.0.0/8", Board: "/a/", Reason: "off topic"})
+	_, _ = store.Add(Ban{Range: "5.6.7.8", Reason: "expired", Expires: time.Now().Add(-time.Minute)})
+
+	tests := []struct {
+		name       string
+		ip, board  string
+		wantReason string
+	}{
+		{name: "global ban", ip: "1.2.3.4", board: "/a/", wantReason: "spam"},
+		{name: "range ban on its board", ip: "10.1.2.3", board: "/a/", wantReason: "off topic"},
+		{name: "range ban on another board", ip: "10.1.2.3", board: "/b/"},
+		{name: "outside every range", ip: "1.2.3.5", board: "/a/"},
+		{name: "expired ban", ip: "5.6.7.8", board: "/a/"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, banned, err := store.Find(tt.ip, tt.board)
+			if err != nil {
+				t.Fatalf("Find() error = %v", err)
+			}
+			if banned != (tt.wantReason != "") || got.Reason != tt.wantReason {
+				t.Errorf("Find() = %v, %t, want ban for %q", got, banned, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestStore_ListAndLift(t *testing.T) {
+	db := data.NewMemoryDB()
+	store := NewStore(db, db)
+	first, _ := store.Add(Ban{Range: "1.2.3.4"})
+	second, _ := store.Add(Ban{Range: "5.6.7.8", Expires: time.Now().Add(time.Hour)})
+	_, _ = store.Add(Ban{Range: "9.9.9.9", Expires: time.Now().Add(-time.Hour)})
+
+	bans, err := store.List()
+	if err != nil || len(bans) != 2 || bans[0].ID != second.ID || bans[1].ID != first.ID {
+		t.Errorf("List() = %v, %v, want the unexpired bans newest first", bans, err)
+	}
+
+	if err := store.Lift(first.ID); err != nil {
+		t.Errorf("Lift() error = %v", err)
+	}
+	if err := store.Lift(first.ID); err == nil {
+		t.Errorf("Lift() expected an error lifting a ban twice")
+	}
+	if _, banned, _ := store.Find("1.2.3.4", "/a/"); banned {
+		t.Errorf("expected lifted ban to no longer apply")
+	}
+}
+
+func TestStore_Add_invalidRange(t *testing.T) {
+	db := data.NewMemoryDB()
+
+	if _, err := NewStore(db, db).Add(Ban{Range: "nowhere"}); err == nil {
+		t.Errorf("Add() expected an error for an invalid range")
+	}
+}
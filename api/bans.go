@@ -0,0 +1,97 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/alice-ws/alice/ban"
+	"github.com/julienschmidt/httprouter"
+	"log"
+	"net/http"
+	"time"
+)
+
+type banResponse struct {
+	Status string    `json:"status"`
+	Bans   []ban.Ban `json:"bans"`
+}
+
+// notBanned refuses requests from banned posters with 403 Forbidden, giving the reason and when the ban expires.
+// It runs before the handler so nothing a banned poster uploads is stored.
+// Requests are refused when the bans cannot be checked, so a DB failure does not lift every ban.
+func notBanned(h httprouter.Handle) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		store, ok := storeFor(w, ps)
+		if !ok {
+			return
+		}
+
+		b, banned, err := posterBans.Find(clientIP(r), store.ID)
+		if err != nil {
+			log.Printf("Could not check bans of %s: %v", clientIP(r), err)
+			w.WriteHeader(http.StatusFailedDependency)
+			_ = json.NewEncoder(w).Encode(boardResponse{Status: "FAILURE"})
+			return
+		}
+		if banned {
+			w.WriteHeader(http.StatusForbidden)
+			_ = json.NewEncoder(w).Encode(boardResponse{Status: "FAILURE", Code: "BANNED", Error: b.Reason, Ban: &b})
+			return
+		}
+		h(w, r, ps)
+	}
+}
+
+func getPosterBansHandler(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
+	bans, err := posterBans.List()
+	if err != nil {
+		w.WriteHeader(http.StatusFailedDependency)
+		_ = json.NewEncoder(w).Encode(banResponse{Status: "FAILURE"})
+		return
+	}
+
+	addHeaders(w)
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(banResponse{Status: "SUCCESS", Bans: bans})
+}
+
+// Bans the range in the form. The ban is on every board unless a board is given,
+// and is permanent unless a duration such as 72h is given.
+func addPosterBanHandler(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	b := ban.Ban{Range: r.FormValue("range"), Reason: r.FormValue("reason")}
+	if name := r.FormValue("board"); name != "" {
+		b.Board = "/" + name + "/"
+		if _, ok := boards[b.Board]; !ok {
+			badRequest(errors.New("no such board "+b.Board), w)
+			return
+		}
+	}
+	if duration := r.FormValue("duration"); duration != "" {
+		d, err := time.ParseDuration(duration)
+		if badRequest(err, w) {
+			return
+		}
+		b.Expires = time.Now().Add(d)
+	}
+
+	b, err := posterBans.Add(b)
+	if badRequest(err, w) {
+		return
+	}
+
+	logModeration(r, "Banned %s on board %q until %v: %s", b.Range, b.Board, b.Expires, b.Reason)
+	addHeaders(w)
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(banResponse{Status: "SUCCESS", Bans: []ban.Ban{b}})
+}
+
+func liftPosterBanHandler(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	ID := r.URL.Query().Get("id")
+	if notFound(posterBans.Lift(ID), w) {
+		return
+	}
+
+	logModeration(r, "Lifted ban %s", ID)
+	addHeaders(w)
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(banResponse{Status: "SUCCESS", Bans: []ban.Ban{}})
+}
@@ -3,10 +3,12 @@ package main
 import (
 	"encoding/json"
 	"errors"
+	"github.com/alice-ws/alice/ban"
 	"github.com/alice-ws/alice/board"
 	"github.com/julienschmidt/httprouter"
 	"log"
 	"net/http"
+	"strconv"
 )
 
 type userResponse struct {
@@ -14,6 +16,7 @@ type userResponse struct {
 	Username string `json:"username"`
 	Error    string `json:"error"`
 	Token    string `json:"token"`
+	Role     string `json:"role,omitempty"`
 }
 
 type boardResponse struct {
@@ -21,6 +24,13 @@ type boardResponse struct {
 	No     string       `json:"no"`
 	Thread board.Thread `json:"thread"`
 	Type   string       `json:"type"`
+	// Code identifies why a request was rejected, along with the error describing it.
+	Code  string `json:"code,omitempty"`
+	Error string `json:"error,omitempty"`
+	// RetryAfter is the number of seconds to wait before trying a rate limited request again.
+	RetryAfter int `json:"retry_after,omitempty"`
+	// Ban is the ban refusing the request, if the poster is banned.
+	Ban *ban.Ban `json:"ban,omitempty"`
 }
 
 const (
@@ -28,14 +38,75 @@ const (
 	Post   = "POST"
 )
 
-func getAllThreadsHandler(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
-	t, err := threadStore.GetAllThreads()
+// Returns the store of the board named in the route, or of the default board for routes without a board.
+// Writes a not found response if there is no such board.
+func storeFor(w http.ResponseWriter, ps httprouter.Params) (*board.Store, bool) {
+	boardID := defaultBoardID
+	if name := ps.ByName("board"); name != "" {
+		boardID = "/" + name + "/"
+	}
+
+	store, ok := boards[boardID]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		_ = json.NewEncoder(w).Encode(boardResponse{Status: "FAILURE"})
+	}
+	return store, ok
+}
+
+func getBoardsHandler(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
+	settings := make(map[string]board.Settings)
+	for boardID, store := range boards {
+		settings[boardID] = store.Settings()
+	}
+
+	addHeaders(w)
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(settings)
+}
+
+func getBoardHandler(w http.ResponseWriter, _ *http.Request, ps httprouter.Params) {
+	store, ok := storeFor(w, ps)
+	if !ok {
+		return
+	}
+
+	addHeaders(w)
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(store.Settings())
+}
+
+func getAllThreadsHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	store, ok := storeFor(w, ps)
+	if !ok {
+		return
+	}
+
+	page, err := queryInt(r, "page", 1)
+	if badRequest(err, w) {
+		return
+	}
+	perPage, err := queryInt(r, "perPage", store.Settings().ThreadsPerPage)
+	if badRequest(err, w) {
+		return
+	}
+	if page < 1 || perPage < 1 {
+		badRequest(errors.New("pages and threads per page start at 1"), w)
+		return
+	}
+	if perPage > store.Settings().MaxThreads {
+		perPage = store.Settings().MaxThreads
+	}
+	t, err := store.GetPage(page, perPage)
 
 	if err != nil {
 		w.WriteHeader(http.StatusNotFound)
 		_ = json.NewEncoder(w).Encode(boardResponse{Status: "FAILURE"})
 		return
 	}
+	if !moderating(r) {
+		t = t.WithoutIPHashes()
+	}
 
 	addHeaders(w)
 	w.WriteHeader(http.StatusOK)
@@ -43,7 +114,33 @@ func getAllThreadsHandler(w http.ResponseWriter, _ *http.Request, _ httprouter.P
 
 }
 
-func addThreadHandler(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+func getCatalogHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	store, ok := storeFor(w, ps)
+	if !ok {
+		return
+	}
+
+	catalog, err := store.GetCatalog(r.URL.Query().Get("sort"))
+	if err == board.ErrUnknownSort {
+		badRequest(err, w)
+		return
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		_ = json.NewEncoder(w).Encode(boardResponse{Status: "FAILURE"})
+		return
+	}
+
+	addHeaders(w)
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(catalog)
+}
+
+func addThreadHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	store, ok := storeFor(w, ps)
+	if !ok {
+		return
+	}
 	err := r.ParseMultipartForm(10 << 20)
 
 	if badRequest(err, w) {
@@ -54,30 +151,29 @@ func addThreadHandler(w http.ResponseWriter, r *http.Request, _ httprouter.Param
 	subject := r.FormValue("subject")
 	comment := r.FormValue("comment")
 	post := board.CreatePost(name, email, comment)
+	post.IPHash = ipHash(clientIP(r))
 
 	_, header, err := r.FormFile("image")
 	if badRequest(err, w) {
 		return
 	}
-	image, err := header.Open()
-	if badRequest(err, w) {
+	post, release, err := attachUpload(post, header, store)
+	if uploadRejected(err, w) {
 		return
 	}
+	defer release()
 
-	URI, err := mediaRepo.Store(image, dependencyManagement.ImageGroup(), mediaRepo.GenerateUniqueName(header.Filename), header.Size)
-
-	post.Filename = header.Filename
-
-	if badRequest(err, w) {
+	if !post.IsValid(store.Settings()) {
+		badRequest(errors.New("Invalid Post: "+post.String()), w)
 		return
 	}
 
-	post.Image = URI
-
 	log.Printf("Add Thread: %v with subject %s", post, subject)
 	t := board.NewThread(post, subject)
 
-	_, err = threadStore.AddThread(t)
+	_, err = store.AddThread(t)
+	// The thread now references its media, which is unlocked before pruning as pruning may move it
+	release()
 
 	if err != nil {
 		w.WriteHeader(http.StatusFailedDependency)
@@ -85,6 +181,8 @@ func addThreadHandler(w http.ResponseWriter, r *http.Request, _ httprouter.Param
 		return
 	}
 
+	prune(store)
+
 	addHeaders(w)
 	w.WriteHeader(http.StatusCreated)
 	_ = json.NewEncoder(w).Encode(boardResponse{
@@ -92,6 +190,19 @@ func addThreadHandler(w http.ResponseWriter, r *http.Request, _ httprouter.Param
 	})
 }
 
+// Returns the integer query parameter, or the default value if it is not given.
+func queryInt(r *http.Request, name string, defaultValue int) (int, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return defaultValue, nil
+	}
+	i, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, errors.New("invalid " + name + ": " + value)
+	}
+	return i, nil
+}
+
 func badRequest(err error, w http.ResponseWriter) bool {
 	if err != nil {
 		log.Printf("Error: %s", err.Error())
@@ -102,7 +213,11 @@ func badRequest(err error, w http.ResponseWriter) bool {
 	return false
 }
 
-func getThreadHandler(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+func getThreadHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	store, ok := storeFor(w, ps)
+	if !ok {
+		return
+	}
 
 	threadNo := r.URL.Query().Get("no")
 	if threadNo == "" {
@@ -110,13 +225,16 @@ func getThreadHandler(w http.ResponseWriter, r *http.Request, _ httprouter.Param
 		_ = json.NewEncoder(w).Encode(boardResponse{Status: "FAILURE"})
 		return
 	}
-	t, err := threadStore.GetThread(threadNo)
+	t, err := store.GetThread(threadNo)
 
 	if err != nil {
 		w.WriteHeader(http.StatusNotFound)
 		_ = json.NewEncoder(w).Encode(boardResponse{Status: "FAILURE"})
 		return
 	}
+	if !moderating(r) {
+		t = t.WithoutIPHashes()
+	}
 
 	addHeaders(w)
 	w.WriteHeader(http.StatusOK)
@@ -124,7 +242,11 @@ func getThreadHandler(w http.ResponseWriter, r *http.Request, _ httprouter.Param
 
 }
 
-func addPostHandler(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+func addPostHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	store, ok := storeFor(w, ps)
+	if !ok {
+		return
+	}
 	err := r.ParseMultipartForm(10 << 20)
 
 	if badRequest(err, w) {
@@ -137,34 +259,32 @@ func addPostHandler(w http.ResponseWriter, r *http.Request, _ httprouter.Params)
 
 	log.Printf("Creating post with fields %s, %s, %s in thread %s", name, email, comment, thread)
 	post := board.CreatePost(name, email, comment)
+	post.IPHash = ipHash(clientIP(r))
 
 	_, header, err := r.FormFile("image")
 
 	if err == nil {
-		image, err := header.Open()
-		if badRequest(err, w) {
+		var release func()
+		post, release, err = attachUpload(post, header, store)
+		if uploadRejected(err, w) {
 			return
 		}
-
-		URI, err := mediaRepo.Store(image, dependencyManagement.ImageGroup(), mediaRepo.GenerateUniqueName(header.Filename), header.Size)
-
-		post.Filename = header.Filename
-
-		if badRequest(err, w) {
-			return
-		}
-
-		post.Image = URI
+		defer release()
 	}
 
-	if !post.IsValid() {
+	if !post.IsValid(store.Settings()) {
 		badRequest(errors.New("Invalid Post: "+post.String()), w)
 		return
 	}
 
 	log.Printf("Added Post: %v in thread %s", post, thread)
-	_, err = threadStore.AddPost(thread, post)
+	_, err = store.AddPost(thread, post)
 
+	if err == board.ErrArchived {
+		w.WriteHeader(http.StatusForbidden)
+		_ = json.NewEncoder(w).Encode(userResponse{Status: "FAILURE", Error: err.Error()})
+		return
+	}
 	if err != nil {
 		w.WriteHeader(http.StatusFailedDependency)
 		_ = json.NewEncoder(w).Encode(userResponse{Status: "FAILURE", Error: err.Error()})
@@ -0,0 +1,145 @@
+package board
+
+import (
+	"errors"
+	"github.com/alice-ws/alice/data"
+	"time"
+)
+
+// ErrArchived is returned when trying to change a thread that has been archived.
+var ErrArchived = errors.New("thread is archived")
+
+// Returns key for an archived thread that is stored in the DB
+func archiveKey(store *Store, no string) string {
+	return store.ID + ":archive:" + no
+}
+
+// Returns key for the ordered set of archived threads, scored by when they were archived
+func archiveIndexKey(store *Store) string {
+	return store.ID + ":archive"
+}
+
+// ArchiveThread takes the thread off the board and keeps it, read only, in the board's archive.
+func (store *Store) ArchiveThread(no string) (Thread, error) {
+	thread, err := store.GetThread(no)
+	if err != nil {
+		return Thread{}, err
+	}
+
+	err = store.db.Set(data.NewKeyValuePair(archiveKey(store, no), thread.String()))
+	if err != nil {
+		return Thread{}, err
+	}
+	err = store.threads.SetOrdered(data.NewKeyValuePair(archiveIndexKey(store), no), score(time.Now()))
+	if err != nil {
+		return Thread{}, err
+	}
+
+	err = store.db.Remove(threadKey(store, no))
+	if err != nil {
+		return Thread{}, err
+	}
+	_ = store.db.Remove(catalogKey(store, no))
+	// Archived posts keep using their media, so only the index of files on the board is changed
+	store.unindexFiles(thread.Posts()...)
+	err = store.threads.RemoveOrdered(data.NewKeyValuePair(store.ID, no))
+	return thread, err
+}
+
+// GetArchivedThreads returns the archived threads, most recently archived first.
+func (store *Store) GetArchivedThreads() ([]Thread, error) {
+	threads := make([]Thread, 0)
+	for _, no := range store.threads.GetAllOrderedByScore(archiveIndexKey(store)) {
+		thread, err := store.GetArchivedThread(no)
+		if err != nil {
+			return []Thread{}, errors.New("error getting archived threads")
+		}
+		threads = append(threads, thread)
+	}
+	return threads, nil
+}
+
+func (store *Store) GetArchivedThread(no string) (Thread, error) {
+	threadString, err := store.db.Get(archiveKey(store, no))
+	if err != nil {
+		return Thread{}, errors.New("no such archived thread found")
+	}
+	return newThreadFrom(threadString)
+}
+
+// MoveArchivedMedia moves the media of every post in the archived thread with the move function,
+// replacing each post's image and thumbnail with the URI they were moved to. Posts keep media that cannot be moved.
+func (store *Store) MoveArchivedMedia(no string, move func(URI string) (string, error)) error {
+	thread, err := store.GetArchivedThread(no)
+	if err != nil {
+		return err
+	}
+
+	var moveErr error
+	moved := make(map[string]string)
+	for _, p := range thread.Posts() {
+		for _, media := range p.Media() {
+			if _, ok := moved[media]; ok {
+				// Posts in the thread sharing a file
+				continue
+			}
+			URI, err := move(media)
+			if err != nil {
+				moveErr = err
+				continue
+			}
+			moved[media] = URI
+		}
+	}
+
+	var before, after []Post
+	err = store.db.Update(archiveKey(store, no), func(current string) (string, error) {
+		thread, err := newThreadFrom(current)
+		if err != nil {
+			return "", err
+		}
+
+		before = thread.Posts()
+		thread.Post = thread.Post.withImageMoved(moved)
+		for i, p := range thread.Replies {
+			thread.Replies[i] = p.withImageMoved(moved)
+		}
+		after = thread.Posts()
+		return thread.String(), nil
+	})
+	if err != nil {
+		return err
+	}
+	// The moved media is indexed before the media it was moved from is unindexed so the thread's media is never unreferenced
+	store.indexMedia(after...)
+	for i, p := range before {
+		for _, URI := range p.Media() {
+			if URI != after[i].Image && URI != after[i].Thumbnail {
+				_ = store.threads.RemoveOrdered(data.NewKeyValuePair(mediaKey(store, URI), p.Key()))
+			}
+		}
+	}
+	return moveErr
+}
+
+// Removes an archived thread. Returns the thread so its media can be removed.
+func (store *Store) deleteArchivedThread(no string) (Thread, error) {
+	thread, err := store.GetArchivedThread(no)
+	if err != nil {
+		return Thread{}, err
+	}
+
+	err = store.db.Remove(archiveKey(store, no))
+	if err != nil {
+		return Thread{}, err
+	}
+	store.unindexMedia(thread.Posts()...)
+	store.unindexPosters(no, thread.Posts()...)
+	err = store.threads.RemoveOrdered(data.NewKeyValuePair(archiveIndexKey(store), no))
+	return thread, err
+}
+
+func (store *Store) isArchived(no string) bool {
+	_, err := store.db.Get(archiveKey(store, no))
+	return err == nil
+}
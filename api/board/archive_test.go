@@ -0,0 +1,82 @@
+package board
+
+import (
+	"errors"
+	"github.com/alice-ws/alice/data"
+	"strconv"
+	"testing"
+)
+
+func TestStore_Prune_archivesThreads(t *testing.T) {
+	store := NewStore("/test/", Settings{MaxThreads: 1, Archive: true}, data.NewMemoryDB(), data.NewMemoryDB())
+	oldest, _ := store.AddThread(thread())
+	_, _ = store.AddThread(thread())
+
+	pruned, err := store.Prune()
+
+	if err != nil || len(pruned) != 1 || pruned[0].No != oldest {
+		t.Fatalf("Prune() = %v, %v, want thread %d", pruned, err, oldest)
+	}
+	no := strconv.FormatUint(oldest, 10)
+	if _, err := store.GetThread(no); err == nil {
+		t.Errorf("expected archived thread to be off the board")
+	}
+	if archived, err := store.GetArchivedThread(no); err != nil || archived.No != oldest {
+		t.Errorf("GetArchivedThread() = %v, %v, want thread %d", archived, err, oldest)
+	}
+	if archived, _ := store.GetArchivedThreads(); len(archived) != 1 {
+		t.Errorf("expected 1 archived thread, got %d", len(archived))
+	}
+}
+
+func TestStore_AddPost_rejectsArchivedThread(t *testing.T) {
+	store := NewStore("/test/", Settings{}, data.NewMemoryDB(), data.NewMemoryDB())
+	no, _ := store.AddThread(thread())
+	threadNo := strconv.FormatUint(no, 10)
+	_, _ = store.ArchiveThread(threadNo)
+
+	if _, err := store.AddPost(threadNo, post()); err != ErrArchived {
+		t.Errorf("AddPost() error = %v, want %v", err, ErrArchived)
+	}
+	if archived, _ := store.GetArchivedThread(threadNo); len(archived.Replies) != 0 {
+		t.Errorf("expected archived thread to be unchanged, got %v", archived)
+	}
+}
+
+func TestStore_DeleteThread_archived(t *testing.T) {
+	store := NewStore("/test/", Settings{}, data.NewMemoryDB(), data.NewMemoryDB())
+	no, _ := store.AddThread(thread())
+	threadNo := strconv.FormatUint(no, 10)
+	_, _ = store.ArchiveThread(threadNo)
+
+	if deleted, err := store.DeleteThread(threadNo); err != nil || deleted.No != no {
+		t.Errorf("DeleteThread() = %v, %v, want thread %d", deleted, err, no)
+	}
+	if archived, _ := store.GetArchivedThreads(); len(archived) != 0 {
+		t.Errorf("expected archive to be empty, got %v", archived)
+	}
+}
+
+func TestStore_MoveArchivedMedia(t *testing.T) {
+	store := NewStore("/test/", Settings{}, data.NewMemoryDB(), data.NewMemoryDB())
+	no, _ := store.AddThread(thread())
+	threadNo := strconv.FormatUint(no, 10)
+	_, _ = store.AddPost(threadNo, post().with("Image", ""))
+	_, _ = store.AddPost(threadNo, post().with("Image", "images/1.png"))
+	_, _ = store.ArchiveThread(threadNo)
+
+	err := store.MoveArchivedMedia(threadNo, func(URI string) (string, error) {
+		if URI == "images/1.png" {
+			return "", errors.New("cannot move")
+		}
+		return "archive/" + URI, nil
+	})
+
+	if err == nil {
+		t.Errorf("expected error for media that could not be moved")
+	}
+	archived, _ := store.GetArchivedThread(threadNo)
+	if archived.Image != "archive/"+post().Image || archived.Replies[0].Image != "" || archived.Replies[1].Image != "images/1.png" {
+		t.Errorf("unexpected images after move: %s, %s, %s", archived.Image, archived.Replies[0].Image, archived.Replies[1].Image)
+	}
+}
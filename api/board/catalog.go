@@ -0,0 +1,174 @@
+package board
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/alice-ws/alice/data"
+	"log"
+	"sort"
+	"time"
+)
+
+// Ways of sorting the catalog
+const (
+	SortByBump      = "bump"
+	SortByCreation  = "created"
+	SortByReplies   = "replies"
+	SortByLastReply = "lastReply"
+)
+
+// ErrUnknownSort is returned when the catalog is asked for in an order it cannot be sorted by.
+var ErrUnknownSort = errors.New("unknown catalog sort")
+
+// Length in characters the OP's comment is truncated to in the catalog
+const catalogCommentLength = 200
+
+// CatalogEntry is a compact summary of a thread for the catalog.
+// Entries are stored alongside threads so the catalog can be built without reading every reply.
+type CatalogEntry struct {
+	No         uint64    `json:"no"`
+	Subject    string    `json:"subject"`
+	Comment    string    `json:"comment"`
+	Thumbnail  string    `json:"thumbnail"`
+	ReplyCount int       `json:"reply_count"`
+	ImageCount int       `json:"image_count"`
+	Created    time.Time `json:"created"`
+	LastBump   time.Time `json:"last_bump"`
+	LastReply  time.Time `json:"last_reply"`
+}
+
+func (e CatalogEntry) String() string {
+	bytes, _ := json.Marshal(e)
+	return string(bytes)
+}
+
+func newCatalogEntryFrom(mjson string) (CatalogEntry, error) {
+	var e CatalogEntry
+	err := json.Unmarshal([]byte(mjson), &e)
+	if err != nil {
+		return CatalogEntry{}, errors.New("cannot parse json" + err.Error())
+	}
+	return e, nil
+}
+
+// Returns key for the catalog entry of a thread that is stored in the DB
+func catalogKey(store *Store, no string) string {
+	return store.ID + ":catalog:" + no
+}
+
+// Returns the catalog entry summarising the whole thread.
+func (store *Store) catalogEntryFrom(t Thread) CatalogEntry {
+	preview := t.preview(0)
+	e := CatalogEntry{
+		No:         t.No,
+		Subject:    t.Subject,
+		Comment:    truncate(t.Comment, catalogCommentLength),
+		Thumbnail:  t.Thumbnail,
+		ReplyCount: preview.ReplyCount,
+		ImageCount: preview.ImageCount,
+		Created:    t.Timestamp,
+		LastBump:   t.Timestamp,
+		LastReply:  t.Timestamp,
+	}
+	for i, reply := range t.Replies {
+		e.LastReply = reply.Timestamp
+		if store.bumps(reply, i+1) {
+			e.LastBump = reply.Timestamp
+		}
+	}
+	return e
+}
+
+// GetCatalog returns an entry for every thread on the board sorted by one of the catalog sorts.
+func (store *Store) GetCatalog(sortBy string) ([]CatalogEntry, error) {
+	var less func(a, b CatalogEntry) bool
+	switch sortBy {
+	case SortByBump, "":
+		// Threads are already in bump order
+	case SortByCreation:
+		less = func(a, b CatalogEntry) bool { return a.No > b.No }
+	case SortByReplies:
+		less = func(a, b CatalogEntry) bool { return a.ReplyCount > b.ReplyCount }
+	case SortByLastReply:
+		less = func(a, b CatalogEntry) bool { return a.LastReply.After(b.LastReply) }
+	default:
+		return nil, ErrUnknownSort
+	}
+
+	nos := store.threads.GetAllOrderedByScore(store.ID)
+	entries := make([]CatalogEntry, 0, len(nos))
+	for _, no := range nos {
+		entry, err := store.catalogEntry(no)
+		if err != nil {
+			return nil, errors.New("error getting catalog")
+		}
+		entries = append(entries, entry)
+	}
+
+	if less != nil {
+		sort.SliceStable(entries, func(i, j int) bool {
+			return less(entries[i], entries[j])
+		})
+	}
+	return entries, nil
+}
+
+// Returns the stored catalog entry of the thread.
+// Threads stored before the catalog existed have their entry built from the thread and stored.
+func (store *Store) catalogEntry(no string) (CatalogEntry, error) {
+	entryString, err := store.db.Get(catalogKey(store, no))
+	if err == nil {
+		return newCatalogEntryFrom(entryString)
+	}
+
+	thread, err := store.GetThread(no)
+	if err != nil {
+		return CatalogEntry{}, err
+	}
+	entry := store.catalogEntryFrom(thread)
+	return entry, store.db.Set(data.NewKeyValuePair(catalogKey(store, no), entry.String()))
+}
+
+// Adds the reply to the thread's catalog entry. Only the counts and times change,
+// so replies added concurrently are all counted whichever order they are applied in.
+func (store *Store) addToCatalog(threadNo string, reply Post, bumped bool) {
+	err := store.db.Update(catalogKey(store, threadNo), func(current string) (string, error) {
+		entry, err := newCatalogEntryFrom(current)
+		if err != nil {
+			return "", err
+		}
+
+		entry.ReplyCount++
+		if reply.Image != "" {
+			entry.ImageCount++
+		}
+		if reply.Timestamp.After(entry.LastReply) {
+			entry.LastReply = reply.Timestamp
+		}
+		if bumped && reply.Timestamp.After(entry.LastBump) {
+			entry.LastBump = reply.Timestamp
+		}
+		return entry.String(), nil
+	})
+	if err != nil {
+		log.Printf("Could not add post %d to catalog of thread %s: %v", reply.No, threadNo, err)
+	}
+}
+
+// Replaces the thread's catalog entry with one built from the thread.
+func (store *Store) refreshCatalog(t Thread) {
+	entry := store.catalogEntryFrom(t)
+	err := store.db.Set(data.NewKeyValuePair(catalogKey(store, t.Key()), entry.String()))
+	if err != nil {
+		log.Printf("Could not refresh catalog of thread %d: %v", t.No, err)
+	}
+}
+
+// Returns the string cut to at most the given number of characters.
+func truncate(s string, length int) string {
+	runes := []rune(s)
+	if len(runes) <= length {
+		return s
+	}
+	return string(runes[:length])
+}
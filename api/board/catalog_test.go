@@ -0,0 +1,116 @@
+package board
+
+import (
+	"github.com/alice-ws/alice/data"
+	"strings"
+	"testing"
+)
+
+func TestStore_GetCatalog(t *testing.T) {
+	store := NewStore("/test/", Settings{}, data.NewMemoryDB(), data.NewMemoryDB())
+	for i := 0; i < 3; i++ {
+		_, _ = store.AddThread(thread())
+	}
+	_, _ = store.AddPost("1", post())
+	_, _ = store.AddPost("0", post().with("Email", "sage"))
+	_, _ = store.AddPost("0", post().with("Email", "sage").with("Image", ""))
+
+	tests := []struct {
+		sort string
+		want []uint64
+	}{
+		{sort: "", want: []uint64{1, 2, 0}},
+		{sort: SortByBump, want: []uint64{1, 2, 0}},
+		{sort: SortByCreation, want: []uint64{2, 1, 0}},
+		{sort: SortByReplies, want: []uint64{0, 1, 2}},
+		{sort: SortByLastReply, want: []uint64{0, 1, 2}},
+	}
+	for _, tt := range tests {
+		t.Run("sorted by "+tt.sort, func(t *testing.T) {
+			got, err := store.GetCatalog(tt.sort)
+			if err != nil {
+				t.Fatalf("GetCatalog() error = %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("GetCatalog() got %d entries, want %d", len(got), len(tt.want))
+			}
+			for i, no := range tt.want {
+				if got[i].No != no {
+					t.Errorf("entry %d = %d, want %d", i, got[i].No, no)
+				}
+			}
+		})
+	}
+}
+
+func TestStore_GetCatalog_entry(t *testing.T) {
+	store := NewStore("/test/", Settings{}, data.NewMemoryDB(), data.NewMemoryDB())
+	op := thread()
+	op.Comment = strings.Repeat("é", catalogCommentLength+1)
+	op.Thumbnail = "/path/0s"
+	_, _ = store.AddThread(op)
+	_, _ = store.AddPost("0", post())
+	_, _ = store.AddPost("0", post().with("Image", "").with("Email", "sage"))
+
+	catalog, _ := store.GetCatalog(SortByBump)
+	got := catalog[0]
+	thread, _ := store.GetThread("0")
+
+	if got.Subject != "A subject" || got.Thumbnail != "/path/0s" {
+		t.Errorf("entry subject and thumbnail = %q %q, want %q %q", got.Subject, got.Thumbnail, "A subject", "/path/0s")
+	}
+	if got.Comment != strings.Repeat("é", catalogCommentLength) {
+		t.Errorf("entry comment = %q, want the first %d characters", got.Comment, catalogCommentLength)
+	}
+	if got.ReplyCount != 2 || got.ImageCount != 1 {
+		t.Errorf("entry counts = %d replies %d images, want 2 replies 1 image", got.ReplyCount, got.ImageCount)
+	}
+	if !got.Created.Equal(thread.Timestamp) || !got.LastBump.Equal(thread.Replies[0].Timestamp) || !got.LastReply.Equal(thread.Replies[1].Timestamp) {
+		t.Errorf("entry times = %v %v %v, want creation, first reply and last reply", got.Created, got.LastBump, got.LastReply)
+	}
+}
+
+func TestStore_GetCatalog_rebuildsMissingEntries(t *testing.T) {
+	db := data.NewMemoryDB()
+	store := NewStore("/test/", Settings{}, db, db)
+	_, _ = store.AddThread(thread())
+	_ = db.Remove(catalogKey(store, "0"))
+	_, _ = store.AddPost("0", post())
+
+	catalog, err := store.GetCatalog(SortByBump)
+
+	if err != nil || len(catalog) != 1 || catalog[0].ReplyCount != 1 {
+		t.Errorf("GetCatalog() = %v, %v, want the rebuilt entry with 1 reply", catalog, err)
+	}
+	if _, err := db.Get(catalogKey(store, "0")); err != nil {
+		t.Errorf("rebuilt entry was not stored: %v", err)
+	}
+}
+
+func TestStore_GetCatalog_moderation(t *testing.T) {
+	store := NewStore("/test/", Settings{}, data.NewMemoryDB(), data.NewMemoryDB())
+	_, _ = store.AddThread(NewThread(post().with("Thumbnail", "/path/0s"), "A subject"))
+	_, _ = store.AddThread(thread())
+	_, _ = store.AddPost("0", post())
+	_, _ = store.AddPost("0", post())
+
+	_, _ = store.DeletePost("0", 2)
+	_, _ = store.DeleteFile("0", 0)
+	_, _ = store.DeleteThread("1")
+
+	catalog, _ := store.GetCatalog(SortByBump)
+	if len(catalog) != 1 {
+		t.Fatalf("GetCatalog() got %d entries, want 1", len(catalog))
+	}
+	if got := catalog[0]; got.ReplyCount != 1 || got.ImageCount != 1 || got.Thumbnail != "" {
+		t.Errorf("entry = %+v, want 1 reply with 1 image and no thumbnail", got)
+	}
+}
+
+func TestStore_GetCatalog_unknownSort(t *testing.T) {
+	store := NewStore("/test/", Settings{}, data.NewMemoryDB(), data.NewMemoryDB())
+
+	if _, err := store.GetCatalog("random"); err != ErrUnknownSort {
+		t.Errorf("GetCatalog() error = %v, want %v", err, ErrUnknownSort)
+	}
+}
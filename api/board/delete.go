@@ -0,0 +1,111 @@
+package board
+
+import (
+	"errors"
+	"github.com/alice-ws/alice/data"
+	"strconv"
+)
+
+// DeleteThread removes the thread and takes it off the board, or out of the archive if it was archived.
+// The deleted thread is returned so its media can be removed.
+func (store *Store) DeleteThread(no string) (Thread, error) {
+	thread, err := store.GetThread(no)
+	if err != nil && store.isArchived(no) {
+		return store.deleteArchivedThread(no)
+	}
+	if err != nil {
+		return Thread{}, err
+	}
+
+	err = store.db.Remove(threadKey(store, no))
+	if err != nil {
+		return Thread{}, err
+	}
+	_ = store.db.Remove(catalogKey(store, no))
+	store.unindexFiles(thread.Posts()...)
+	store.unindexMedia(thread.Posts()...)
+	store.unindexPosters(no, thread.Posts()...)
+	err = store.threads.RemoveOrdered(data.NewKeyValuePair(store.ID, no))
+	return thread, err
+}
+
+// DeletePost removes a reply from the thread, along with any record of the reply quoting other posts.
+// The deleted reply is returned so its media can be removed.
+func (store *Store) DeletePost(threadNo string, no uint64) (Post, error) {
+	var deleted Post
+	var updated Thread
+	err := store.db.Update(threadKey(store, threadNo), func(current string) (string, error) {
+		thread, err := newThreadFrom(current)
+		if err != nil {
+			return "", err
+		}
+
+		index, p := thread.getReplyWithPostNo(no)
+		if index < 0 {
+			return "", errors.New("no such post found in thread " + threadNo)
+		}
+		deleted = p
+
+		replies := make([]Post, 0, len(thread.Replies)-1)
+		replies = append(replies, thread.Replies[:index]...)
+		thread.Replies = append(replies, thread.Replies[index+1:]...)
+		updated = thread.withoutQuotesBy(no)
+		return updated.String(), nil
+	})
+	if err == nil {
+		store.refreshCatalog(updated)
+		store.unindexFiles(deleted)
+		store.unindexMedia(deleted)
+		store.unindexPosters(threadNo, deleted)
+	}
+	return deleted, err
+}
+
+// DeleteFile removes the file from the post, which can be the thread's OP or a reply.
+// The post is returned as it was before the file was removed so the media can be removed.
+func (store *Store) DeleteFile(threadNo string, no uint64) (Post, error) {
+	var withFile Post
+	var updated Thread
+	err := store.db.Update(threadKey(store, threadNo), func(current string) (string, error) {
+		thread, err := newThreadFrom(current)
+		if err != nil {
+			return "", err
+		}
+
+		index, p := thread.getReplyWithPostNo(no)
+		if thread.No == no {
+			p = thread.Post
+		} else if index < 0 {
+			return "", errors.New("no such post found in thread " + threadNo)
+		}
+		if p.Image == "" {
+			return "", errors.New("post " + strconv.FormatUint(no, 10) + " has no file")
+		}
+
+		withFile = p
+		if thread.No == no {
+			thread.Post = p.withoutFile()
+		} else {
+			thread.Replies[index] = p.withoutFile()
+		}
+		updated = thread
+		return thread.String(), nil
+	})
+	if err == nil {
+		store.refreshCatalog(updated)
+		store.unindexFiles(withFile)
+		store.unindexMedia(withFile)
+	}
+	return withFile, err
+}
+
+// Returns a copy of the thread with the post removed from every post's quoted by list.
+func (t Thread) withoutQuotesBy(no uint64) Thread {
+	t.Post = t.Post.withoutQuoteBy(no)
+	replies := make([]Post, len(t.Replies))
+	for i, p := range t.Replies {
+		replies[i] = p.withoutQuoteBy(no)
+	}
+	t.Replies = replies
+	return t
+}
@@ -0,0 +1,83 @@
+package board
+
+import (
+	"github.com/alice-ws/alice/data"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestStore_DeleteThread(t *testing.T) {
+	store := NewStore("/test/", Settings{}, data.NewMemoryDB(), data.NewMemoryDB())
+	no, _ := store.AddThread(thread())
+	other, _ := store.AddThread(thread())
+
+	deleted, err := store.DeleteThread(strconv.FormatUint(no, 10))
+
+	if err != nil || deleted.No != no {
+		t.Errorf("DeleteThread() = %v, %v, want thread %d", deleted, err, no)
+	}
+	if _, err := store.GetThread(strconv.FormatUint(no, 10)); err == nil {
+		t.Errorf("expected thread to be deleted")
+	}
+	threads, _ := store.GetAllThreads()
+	if len(threads) != 1 || threads[0].No != other {
+		t.Errorf("expected only thread %d on the board, got %v", other, threads)
+	}
+}
+
+func TestStore_DeletePost_removesQuotes(t *testing.T) {
+	store := NewStore("/test/", Settings{}, data.NewMemoryDB(), data.NewMemoryDB())
+	no, _ := store.AddThread(thread())
+	threadNo := strconv.FormatUint(no, 10)
+	first, _ := store.AddPost(threadNo, post())
+	second, _ := store.AddPost(threadNo, post().with("Comment", ">>"+threadNo+"\n>>"+strconv.FormatUint(first, 10)))
+	third, _ := store.AddPost(threadNo, post().with("Comment", ">>"+threadNo))
+
+	deleted, err := store.DeletePost(threadNo, second)
+
+	if err != nil || deleted.No != second {
+		t.Fatalf("DeletePost() = %v, %v, want post %d", deleted, err, second)
+	}
+	got, _ := store.GetThread(threadNo)
+	if len(got.Replies) != 2 || got.Replies[0].No != first || got.Replies[1].No != third {
+		t.Errorf("expected replies %d and %d, got %v", first, third, got.Replies)
+	}
+	if !reflect.DeepEqual(got.QuotedBy, []uint64{third}) {
+		t.Errorf("expected OP to be quoted by %d only, got %v", third, got.QuotedBy)
+	}
+	if len(got.Replies[0].QuotedBy) != 0 {
+		t.Errorf("expected first reply to not be quoted, got %v", got.Replies[0].QuotedBy)
+	}
+}
+
+func TestStore_DeletePost_missingPost(t *testing.T) {
+	store := NewStore("/test/", Settings{}, data.NewMemoryDB(), data.NewMemoryDB())
+	no, _ := store.AddThread(thread())
+
+	if _, err := store.DeletePost(strconv.FormatUint(no, 10), 99); err == nil {
+		t.Errorf("expected error deleting missing post")
+	}
+}
+
+func TestStore_DeleteFile(t *testing.T) {
+	store := NewStore("/test/", Settings{}, data.NewMemoryDB(), data.NewMemoryDB())
+	no, _ := store.AddThread(thread())
+	threadNo := strconv.FormatUint(no, 10)
+	reply, _ := store.AddPost(threadNo, post())
+
+	for _, postNo := range []uint64{no, reply} {
+		withFile, err := store.DeleteFile(threadNo, postNo)
+		if err != nil || withFile.Image != post().Image {
+			t.Errorf("DeleteFile(%d) = %v, %v, want post with file", postNo, withFile, err)
+		}
+		if _, err := store.DeleteFile(threadNo, postNo); err == nil {
+			t.Errorf("expected error deleting file of post %d twice", postNo)
+		}
+	}
+
+	got, _ := store.GetThread(threadNo)
+	if got.Image != "" || got.Replies[0].Image != "" || got.Replies[0].Comment != post().Comment {
+		t.Errorf("expected files to be removed and posts kept, got %v", got)
+	}
+}
@@ -0,0 +1,33 @@
+package board
+
+import (
+	"github.com/alice-ws/alice/data"
+)
+
+// Returns key for the set of posts on the board with a file with the hash, scored by when they were posted
+func hashKey(store *Store, hash string) string {
+	return store.ID + ":hash:" + hash
+}
+
+// HasFile returns whether a post on the board has a file with the hash. Archived posts are not included.
+func (store *Store) HasFile(hash string) bool {
+	return store.threads.CountOrdered(hashKey(store, hash)) > 0
+}
+
+// Adds the posts with files to the set of posts for their file's hash.
+func (store *Store) indexFiles(posts ...Post) {
+	for _, p := range posts {
+		if p.Hash != "" {
+			_ = store.threads.SetOrdered(data.NewKeyValuePair(hashKey(store, p.Hash), p.Key()), score(p.Timestamp))
+		}
+	}
+}
+
+// Removes the posts from the set of posts for their file's hash.
+func (store *Store) unindexFiles(posts ...Post) {
+	for _, p := range posts {
+		if p.Hash != "" {
+			_ = store.threads.RemoveOrdered(data.NewKeyValuePair(hashKey(store, p.Hash), p.Key()))
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package board
+
+import (
+	"github.com/alice-ws/alice/data"
+	"testing"
+)
+
+func TestStore_HasFile(t *testing.T) {
+	store := NewStore("/test/", Settings{}, data.NewMemoryDB(), data.NewMemoryDB())
+	_, _ = store.AddThread(NewThread(post().with("Hash", "op"), "A subject"))
+	_, _ = store.AddPost("0", post().with("Hash", "shared"))
+	_, _ = store.AddPost("0", post().with("Hash", "shared"))
+	_, _ = store.AddPost("0", post().with("Hash", "file"))
+
+	for _, hash := range []string{"op", "shared", "file"} {
+		if !store.HasFile(hash) {
+			t.Errorf("HasFile(%s) = false, want true", hash)
+		}
+	}
+	if store.HasFile("other") {
+		t.Errorf("HasFile(other) = true, want false")
+	}
+
+	_, _ = store.DeletePost("0", 1)
+	if !store.HasFile("shared") {
+		t.Errorf("expected file to be kept while another post has it")
+	}
+	_, _ = store.DeletePost("0", 2)
+	_, _ = store.DeleteFile("0", 3)
+	if store.HasFile("shared") || store.HasFile("file") {
+		t.Errorf("expected deleted files to be removed")
+	}
+}
@@ -0,0 +1,33 @@
+package board
+
+import (
+	"github.com/alice-ws/alice/data"
+)
+
+// Returns key for the set of posts on the board or in its archive using the media, scored by when they were posted
+func mediaKey(store *Store, URI string) string {
+	return store.ID + ":media:" + URI
+}
+
+// MediaReferences returns the number of posts on the board and in its archive using the media as their image or thumbnail.
+func (store *Store) MediaReferences(URI string) int64 {
+	return store.threads.CountOrdered(mediaKey(store, URI))
+}
+
+// Adds the posts to the set of posts for each of their media.
+func (store *Store) indexMedia(posts ...Post) {
+	for _, p := range posts {
+		for _, URI := range p.Media() {
+			_ = store.threads.SetOrdered(data.NewKeyValuePair(mediaKey(store, URI), p.Key()), score(p.Timestamp))
+		}
+	}
+}
+
+// Removes the posts from the set of posts for each of their media.
+func (store *Store) unindexMedia(posts ...Post) {
+	for _, p := range posts {
+		for _, URI := range p.Media() {
+			_ = store.threads.RemoveOrdered(data.NewKeyValuePair(mediaKey(store, URI), p.Key()))
+		}
+	}
+}
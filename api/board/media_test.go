@@ -0,0 +1,37 @@
+package board
+
+import (
+	"github.com/alice-ws/alice/data"
+	"testing"
+)
+
+func TestStore_MediaReferences(t *testing.T) {
+	store := NewStore("/test/", Settings{Archive: true}, data.NewMemoryDB(), data.NewMemoryDB())
+	op := post().with("Image", "images/op.png").with("Thumbnail", "images/ops.png")
+	_, _ = store.AddThread(NewThread(op, "A subject"))
+	_, _ = store.AddPost("0", post().with("Image", "images/op.png").with("Thumbnail", "images/ops.png"))
+
+	if got := store.MediaReferences("images/op.png"); got != 2 {
+		t.Errorf("MediaReferences() = %d, want both posts", got)
+	}
+
+	_, _ = store.ArchiveThread("0")
+	if got := store.MediaReferences("images/ops.png"); got != 2 {
+		t.Errorf("MediaReferences() = %d, want the archived posts to still use the thumbnail", got)
+	}
+
+	_ = store.MoveArchivedMedia("0", func(URI string) (string, error) {
+		if URI == "images/op.png" {
+			return "archive/op.png", nil
+		}
+		return URI, nil
+	})
+	if store.MediaReferences("images/op.png") != 0 || store.MediaReferences("archive/op.png") != 2 || store.MediaReferences("images/ops.png") != 2 {
+		t.Errorf("expected only the moved media to be referenced where it was moved to")
+	}
+
+	_, _ = store.DeleteThread("0")
+	if store.MediaReferences("archive/op.png") != 0 || store.MediaReferences("images/ops.png") != 0 {
+		t.Errorf("expected media of deleted archived thread to no longer be referenced")
+	}
+}
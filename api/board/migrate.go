@@ -0,0 +1,35 @@
+package board
+
+import (
+	"github.com/alice-ws/alice/data"
+	"log"
+)
+
+// MigrateThreadKeys moves threads stored under their bare post number into the board's namespaced key.
+// Threads are found through the board's ordered set. Threads that already have a namespaced key are left as they are.
+// Returns the number of threads moved.
+func (store *Store) MigrateThreadKeys() (int, error) {
+	migrated := 0
+	for _, no := range store.threads.GetAllOrderedByScore(store.ID) {
+		if _, err := store.db.Get(threadKey(store, no)); err == nil {
+			continue
+		}
+
+		threadString, err := store.db.Get(no)
+		if err != nil {
+			log.Printf("Thread %s of board %s has no stored thread to migrate", no, store.ID)
+			continue
+		}
+
+		err = store.db.Set(data.NewKeyValuePair(threadKey(store, no), threadString))
+		if err != nil {
+			return migrated, err
+		}
+		err = store.db.Remove(no)
+		if err != nil {
+			return migrated, err
+		}
+		migrated++
+	}
+	return migrated, nil
+}
@@ -0,0 +1,41 @@
+package board
+
+import (
+	"github.com/alice-ws/alice/data"
+	"testing"
+)
+
+func TestStore_MigrateThreadKeys(t *testing.T) {
+	db := data.NewMemoryDB()
+	_ = db.Set(data.NewKeyValuePair("0", thread().String()))
+	_ = db.SetOrdered(data.NewKeyValuePair("/test/", "0"), 0)
+	store := NewStore("/test/", Settings{}, db, db)
+
+	migrated, err := store.MigrateThreadKeys()
+
+	if err != nil || migrated != 1 {
+		t.Errorf("MigrateThreadKeys() = %d, %v, want 1", migrated, err)
+	}
+	if _, err := db.Get("0"); err == nil {
+		t.Errorf("expected bare thread key to be removed")
+	}
+	if got, err := store.GetThread("0"); err != nil || got.Subject != thread().Subject {
+		t.Errorf("GetThread() = %v, %v, want migrated thread", got, err)
+	}
+}
+
+func TestStore_MigrateThreadKeys_keepsNamespacedThreads(t *testing.T) {
+	db := data.NewMemoryDB()
+	store := NewStore("/test/", Settings{}, db, db)
+	no, _ := store.AddThread(thread())
+	_ = db.Set(data.NewKeyValuePair("0", "stale"))
+
+	migrated, err := store.MigrateThreadKeys()
+
+	if err != nil || migrated != 0 || no != 0 {
+		t.Errorf("MigrateThreadKeys() = %d, %v, want 0", migrated, err)
+	}
+	if _, err := store.GetThread("0"); err != nil {
+		t.Errorf("GetThread() error = %v", err)
+	}
+}
@@ -0,0 +1,41 @@
+package board
+
+import (
+	"errors"
+)
+
+// Page is one page of the board index.
+type Page struct {
+	Threads    []Preview `json:"threads"`
+	Page       int       `json:"page"`
+	PerPage    int       `json:"per_page"`
+	TotalPages int       `json:"total_pages"`
+}
+
+// GetPage returns previews of the threads on the page of the board, in bump order. Pages start at 1.
+// Only the threads on the page are read from the DB.
+func (store *Store) GetPage(page, perPage int) (Page, error) {
+	if page < 1 || perPage < 1 {
+		return Page{}, errors.New("invalid page")
+	}
+
+	count := store.threads.CountOrdered(store.ID)
+	start := int64((page - 1) * perPage)
+	threads := make([]Preview, 0, perPage)
+	if start < count {
+		for _, no := range store.threads.GetRangeByRank(store.ID, start, start+int64(perPage)-1) {
+			thread, err := store.GetThread(no)
+			if err != nil {
+				return Page{}, errors.New("error getting threads")
+			}
+			threads = append(threads, thread.preview(*store.settings.PreviewReplies))
+		}
+	}
+
+	return Page{
+		Threads:    threads,
+		Page:       page,
+		PerPage:    perPage,
+		TotalPages: int((count + int64(perPage) - 1) / int64(perPage)),
+	}, nil
+}
@@ -0,0 +1,80 @@
+package board
+
+import (
+	"github.com/alice-ws/alice/data"
+	"strconv"
+	"testing"
+)
+
+func TestStore_GetPage(t *testing.T) {
+	store := NewStore("/test/", Settings{}, data.NewMemoryDB(), data.NewMemoryDB())
+	for i := 0; i < 5; i++ {
+		_, _ = store.AddThread(thread())
+	}
+
+	tests := []struct {
+		name          string
+		page, perPage int
+		want          []uint64
+	}{
+		{name: "first page", page: 1, perPage: 2, want: []uint64{4, 3}},
+		{name: "middle page", page: 2, perPage: 2, want: []uint64{2, 1}},
+		{name: "last partial page", page: 3, perPage: 2, want: []uint64{0}},
+		{name: "page after the last", page: 4, perPage: 2, want: []uint64{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := store.GetPage(tt.page, tt.perPage)
+			if err != nil {
+				t.Fatalf("GetPage() error = %v", err)
+			}
+			if got.TotalPages != 3 || got.Page != tt.page || got.PerPage != tt.perPage {
+				t.Errorf("GetPage() metadata = %d of %d with %d per page, want %d of 3 with %d per page", got.Page, got.TotalPages, got.PerPage, tt.page, tt.perPage)
+			}
+			if len(got.Threads) != len(tt.want) {
+				t.Fatalf("GetPage() got %d threads, want %d", len(got.Threads), len(tt.want))
+			}
+			for i, no := range tt.want {
+				if got.Threads[i].No != no {
+					t.Errorf("thread %d = %d, want %d", i, got.Threads[i].No, no)
+				}
+			}
+		})
+	}
+}
+
+func TestStore_GetPage_emptyBoard(t *testing.T) {
+	store := NewStore("/test/", Settings{}, data.NewMemoryDB(), data.NewMemoryDB())
+
+	got, err := store.GetPage(1, 10)
+
+	if err != nil || len(got.Threads) != 0 || got.TotalPages != 0 {
+		t.Errorf("GetPage() = %v, %v, want empty page", got, err)
+	}
+}
+
+func TestStore_GetPage_previewReplies(t *testing.T) {
+	tests := []struct {
+		name    string
+		replies *int
+		want    int
+	}{
+		{name: "default number of replies", want: 5},
+		{name: "configured number of replies", replies: intPtr(2), want: 2},
+		{name: "no replies", replies: intPtr(0), want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewStore("/test/", Settings{PreviewReplies: tt.replies}, data.NewMemoryDB(), data.NewMemoryDB())
+			no, _ := store.AddThread(thread())
+			for i := 0; i < 6; i++ {
+				_, _ = store.AddPost(strconv.FormatUint(no, 10), post())
+			}
+
+			got, _ := store.GetPage(1, 1)
+			if len(got.Threads) != 1 || len(got.Threads[0].Replies) != tt.want || got.Threads[0].ReplyCount != 6 {
+				t.Errorf("GetPage() = %v, want a thread of 6 replies showing %d", got, tt.want)
+			}
+		})
+	}
+}
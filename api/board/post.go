@@ -3,7 +3,6 @@ package board
 import (
 	"encoding/json"
 	"errors"
-	"path/filepath"
 	"strconv"
 	"time"
 )
@@ -12,13 +11,25 @@ type Post struct {
 	No              uint64    `json:"no"`
 	Timestamp       time.Time `json:"timestamp"`
 	Name            string    `json:"name"`
+	Trip            string    `json:"trip"`
+	PosterID        string    `json:"poster_id,omitempty"`
 	Email           string    `json:"email"`
 	Comment         string    `json:"comment"`
 	CommentSegments []Segment `json:"comment_segments"`
 	Image           string    `json:"image"`
-	Filename        string    `json:"filename"`
-	Meta            string    `json:"meta"`
-	QuotedBy        []uint64  `json:"quoted_by"`
+	Thumbnail       string    `json:"thumbnail"`
+	ThumbnailWidth  int       `json:"thumbnail_width"`
+	ThumbnailHeight int       `json:"thumbnail_height"`
+	Width           int       `json:"width"`
+	Height          int       `json:"height"`
+	Size            int64     `json:"size"`
+	// Hash is the hex encoded SHA-256 of the file
+	Hash           string   `json:"hash"`
+	PerceptualHash string   `json:"perceptual_hash"`
+	IPHash         string   `json:"ip_hash,omitempty"`
+	Filename       string   `json:"filename"`
+	Meta           string   `json:"meta"`
+	QuotedBy       []uint64 `json:"quoted_by"`
 }
 
 func (p Post) Key() string {
@@ -68,21 +79,73 @@ func (p Post) quotedBy(postQuotingNo uint64) Post {
 	return p
 }
 
-func (p Post) IsValid() bool {
+// Returns a copy of the post without the post quoting it in its quoted by list.
+func (p Post) withoutQuoteBy(postQuotingNo uint64) Post {
+	quotedBy := make([]uint64, 0, len(p.QuotedBy))
+	for _, no := range p.QuotedBy {
+		if no != postQuotingNo {
+			quotedBy = append(quotedBy, no)
+		}
+	}
+	p.QuotedBy = quotedBy
+	return p
+}
+
+// Media returns the URIs of the post's image and thumbnail, if it has them.
+func (p Post) Media() []string {
+	var URIs []string
+	if p.Image != "" {
+		URIs = append(URIs, p.Image)
+	}
+	if p.Thumbnail != "" {
+		URIs = append(URIs, p.Thumbnail)
+	}
+	return URIs
+}
+
+// Returns a copy of the post with its image and thumbnail replaced by the URIs they were moved to, if they were moved.
+func (p Post) withImageMoved(moved map[string]string) Post {
+	if URI, ok := moved[p.Image]; ok {
+		p.Image = URI
+	}
+	if URI, ok := moved[p.Thumbnail]; ok {
+		p.Thumbnail = URI
+	}
+	return p
+}
+
+// Returns a copy of the post without its file.
+func (p Post) withoutFile() Post {
+	p.Image = ""
+	p.Thumbnail = ""
+	p.ThumbnailWidth = 0
+	p.ThumbnailHeight = 0
+	p.Width = 0
+	p.Height = 0
+	p.Size = 0
+	p.Hash = ""
+	p.PerceptualHash = ""
+	p.Filename = ""
+	return p
+}
+
+// IsValid returns whether the post has content and any file is of a type allowed by the board settings.
+func (p Post) IsValid(settings Settings) bool {
 	if len(p.Comment) < 1 && p.Image == "" {
 		return false
 	}
-	if p.Image != "" && filepath.Ext(p.Filename) != ".png" && filepath.Ext(p.Filename) != ".jpeg" && filepath.Ext(p.Filename) != ".jpg" && filepath.Ext(p.Filename) != ".gif" && filepath.Ext(p.Filename) != ".webm" {
+	if p.Image != "" && !settings.AllowsFile(p.Filename) {
 		return false
 	}
 
 	return true
 }
 
-func (p Post) update(postCount uint64) (Post, []Transform) {
+func (p Post) update(postCount uint64, tripSecret []byte) (Post, []Transform) {
 	post := p
 	post.No = postCount - 1
 
+	post.Name, post.Trip = splitTrip(post.Name, tripSecret)
 	if len(post.Name) < 1 {
 		post.Name = "Anonymous"
 	}
@@ -78,7 +78,7 @@ func TestPost_validate(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := tt.inputPost.IsValid(); got != tt.want {
+			if got := tt.inputPost.IsValid(DefaultSettings("/test/")); got != tt.want {
 				t.Errorf("isValid() = %v, want %v", got, tt.want)
 			}
 		})
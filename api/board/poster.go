@@ -0,0 +1,108 @@
+package board
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"github.com/alice-ws/alice/data"
+	"strconv"
+	"strings"
+)
+
+// posterIDLength is the number of characters in a poster ID.
+const posterIDLength = 8
+
+// PosterPost is a post found by its poster's IP hash, along with where it was posted.
+type PosterPost struct {
+	Board    string `json:"board"`
+	ThreadNo uint64 `json:"thread_no"`
+	Post     Post   `json:"post"`
+}
+
+// Returns key for the set of posts, live or archived, made from the IP hash, scored by when they were posted.
+// Members are the thread no and post no separated by a slash.
+func posterKey(store *Store, ipHash string) string {
+	return store.ID + ":poster:" + ipHash
+}
+
+// PostsByPoster returns the posts on the board, including its archive, made from the IP hash, newest first.
+func (store *Store) PostsByPoster(ipHash string) []PosterPost {
+	posts := make([]PosterPost, 0)
+	for _, member := range store.threads.GetAllOrderedByScore(posterKey(store, ipHash)) {
+		parts := strings.SplitN(member, "/", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		no, err := strconv.ParseUint(parts[1], 10, 64)
+		if err != nil {
+			continue
+		}
+
+		thread, err := store.GetThread(parts[0])
+		if err != nil {
+			thread, err = store.GetArchivedThread(parts[0])
+		}
+		if err != nil {
+			continue
+		}
+		for _, p := range thread.Posts() {
+			if p.No == no {
+				posts = append(posts, PosterPost{Board: store.ID, ThreadNo: thread.No, Post: p})
+			}
+		}
+	}
+	return posts
+}
+
+// Returns a copy of the post with its poster's ID in the thread, if the board gives posters IDs.
+// The ID is derived from the poster's IP hash, the thread and the day the post was made, in UTC,
+// so posters can be followed through a thread without being followed across threads or days.
+func (store *Store) withPosterID(threadNo string, p Post) Post {
+	if !store.settings.PosterIDs || p.IPHash == "" {
+		return p
+	}
+	day := p.Timestamp.UTC().Format("2006-01-02")
+	sum := sha256.Sum256([]byte(p.IPHash + ":" + store.ID + threadNo + ":" + day))
+	p.PosterID = base64.RawURLEncoding.EncodeToString(sum[:])[:posterIDLength]
+	return p
+}
+
+// Adds the posts in the thread to the sets of posts for their IP hash.
+func (store *Store) indexPosters(threadNo string, posts ...Post) {
+	for _, p := range posts {
+		if p.IPHash != "" {
+			_ = store.threads.SetOrdered(data.NewKeyValuePair(posterKey(store, p.IPHash), threadNo+"/"+p.Key()), score(p.Timestamp))
+		}
+	}
+}
+
+// Removes the posts in the thread from the sets of posts for their IP hash.
+func (store *Store) unindexPosters(threadNo string, posts ...Post) {
+	for _, p := range posts {
+		if p.IPHash != "" {
+			_ = store.threads.RemoveOrdered(data.NewKeyValuePair(posterKey(store, p.IPHash), threadNo+"/"+p.Key()))
+		}
+	}
+}
+
+// WithoutIPHashes returns a copy of the thread with no post's IP hash, for showing to the public.
+func (t Thread) WithoutIPHashes() Thread {
+	t.Post.IPHash = ""
+	replies := make([]Post, len(t.Replies))
+	for i, reply := range t.Replies {
+		reply.IPHash = ""
+		replies[i] = reply
+	}
+	t.Replies = replies
+	return t
+}
+
+// WithoutIPHashes returns a copy of the page with no post's IP hash, for showing to the public.
+func (p Page) WithoutIPHashes() Page {
+	threads := make([]Preview, len(p.Threads))
+	for i, preview := range p.Threads {
+		preview.Thread = preview.Thread.WithoutIPHashes()
+		threads[i] = preview
+	}
+	p.Threads = threads
+	return p
+}
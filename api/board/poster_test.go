@@ -0,0 +1,81 @@
+package board
+
+import (
+	"github.com/alice-ws/alice/data"
+	"testing"
+)
+
+func TestStore_PostsByPoster(t *testing.T) {
+	store := NewStore("/test/", Settings{Archive: true}, data.NewMemoryDB(), data.NewMemoryDB())
+	_, _ = store.AddThread(NewThread(post().with("IPHash", "a"), "A subject"))
+	_, _ = store.AddPost("0", post().with("IPHash", "b"))
+	_, _ = store.AddPost("0", post().with("IPHash", "a"))
+	_, _ = store.AddThread(NewThread(post().with("IPHash", "a"), "Another subject"))
+
+	expectPosterPosts(t, store, "a", 3, 2, 0)
+	expectPosterPosts(t, store, "b", 1)
+
+	_, _ = store.DeletePost("0", 2)
+	expectPosterPosts(t, store, "a", 3, 0)
+	_, _ = store.ArchiveThread("0")
+	expectPosterPosts(t, store, "a", 3, 0)
+	_, _ = store.DeleteThread("0")
+	expectPosterPosts(t, store, "a", 3)
+	expectPosterPosts(t, store, "b")
+}
+
+func TestThread_WithoutIPHashes(t *testing.T) {
+	original := NewThread(post().with("IPHash", "a"), "A subject").withReply(post().with("IPHash", "b"))
+
+	public := original.WithoutIPHashes()
+
+	for _, p := range public.Posts() {
+		if p.IPHash != "" {
+			t.Errorf("post %d has IP hash %s, want none", p.No, p.IPHash)
+		}
+	}
+	if original.Replies[0].IPHash != "b" {
+		t.Errorf("expected the original thread to keep its IP hashes")
+	}
+}
+
+func expectPosterPosts(t *testing.T, store *Store, ipHash string, want ...uint64) {
+	t.Helper()
+	got := store.PostsByPoster(ipHash)
+	if len(got) != len(want) {
+		t.Fatalf("PostsByPoster(%s) returned %d posts, want %v", ipHash, len(got), want)
+	}
+	for i, p := range got {
+		if p.Post.No != want[i] || p.Post.IPHash != ipHash || p.Board != "/test/" {
+			t.Errorf("PostsByPoster(%s)[%d] = %v, want post %d", ipHash, i, p, want[i])
+		}
+	}
+}
+
+func TestStore_posterIDs(t *testing.T) {
+	store := NewStore("/test/", Settings{PosterIDs: true}, data.NewMemoryDB(), data.NewMemoryDB())
+	_, _ = store.AddThread(NewThread(post().with("IPHash", "a"), "A subject"))
+	_, _ = store.AddPost("0", post().with("IPHash", "a"))
+	_, _ = store.AddPost("0", post().with("IPHash", "b"))
+	_, _ = store.AddThread(NewThread(post().with("IPHash", "a"), "Another subject"))
+	_, _ = store.AddPost("0", post())
+
+	thread, _ := store.GetThread("0")
+	other, _ := store.GetThread("3")
+	op, same, different, none := thread.PosterID, thread.Replies[0].PosterID, thread.Replies[1].PosterID, thread.Replies[2].PosterID
+	if len(op) != posterIDLength || op != same {
+		t.Errorf("expected a poster to keep their ID in a thread, got %s and %s", op, same)
+	}
+	if op == different || op == other.PosterID {
+		t.Errorf("expected IDs to differ between posters and threads, got %s, %s and %s", op, different, other.PosterID)
+	}
+	if none != "" {
+		t.Errorf("expected no ID for a post without an IP hash, got %s", none)
+	}
+
+	store = NewStore("/test/", Settings{}, data.NewMemoryDB(), data.NewMemoryDB())
+	_, _ = store.AddThread(NewThread(post().with("IPHash", "a"), "A subject"))
+	if thread, _ := store.GetThread("0"); thread.PosterID != "" {
+		t.Errorf("expected no poster IDs on a board without them, got %s", thread.PosterID)
+	}
+}
@@ -0,0 +1,23 @@
+package board
+
+// Preview is a thread as shown on the board index, with only its latest replies.
+type Preview struct {
+	Thread
+	ReplyCount int `json:"reply_count"`
+	ImageCount int `json:"image_count"`
+}
+
+// Returns the preview of the thread with at most the given number of its latest replies.
+func (t Thread) preview(replies int) Preview {
+	p := Preview{Thread: t, ReplyCount: len(t.Replies)}
+	for _, reply := range t.Replies {
+		if reply.Image != "" {
+			p.ImageCount++
+		}
+	}
+
+	if len(t.Replies) > replies {
+		p.Replies = t.Replies[len(t.Replies)-replies:]
+	}
+	return p
+}
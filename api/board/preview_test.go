@@ -0,0 +1,44 @@
+package board
+
+import (
+	"testing"
+)
+
+func TestThread_preview(t *testing.T) {
+	withReplies := func(replies ...Post) Thread {
+		th := thread()
+		for i, p := range replies {
+			th.Replies = append(th.Replies, p.with("No", uint64(i+1)))
+		}
+		return th
+	}
+	textOnly := post().with("Image", "")
+
+	tests := []struct {
+		name       string
+		thread     Thread
+		want       []uint64
+		replyCount int
+		imageCount int
+	}{
+		{name: "thread without replies", thread: thread(), want: []uint64{}},
+		{name: "thread with fewer replies than shown", thread: withReplies(post(), textOnly), want: []uint64{1, 2}, replyCount: 2, imageCount: 1},
+		{name: "thread with more replies than shown", thread: withReplies(post(), post(), textOnly, post()), want: []uint64{2, 3, 4}, replyCount: 4, imageCount: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.thread.preview(3)
+			if got.ReplyCount != tt.replyCount || got.ImageCount != tt.imageCount {
+				t.Errorf("preview() counts = %d replies, %d images, want %d, %d", got.ReplyCount, got.ImageCount, tt.replyCount, tt.imageCount)
+			}
+			if len(got.Replies) != len(tt.want) {
+				t.Fatalf("preview() has %d replies, want %d", len(got.Replies), len(tt.want))
+			}
+			for i, no := range tt.want {
+				if got.Replies[i].No != no {
+					t.Errorf("reply %d = %d, want %d", i, got.Replies[i].No, no)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package board
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// Settings configure a single board. They are read from the boards map in the configuration.
+type Settings struct {
+	Name string `json:"name"`
+	// Maximum size of an uploaded file in bytes
+	MaxFileSize int64 `json:"maxFileSize"`
+	// Maximum width and height in pixels of an uploaded image. Images are decoded whole to be thumbnailed, so these bound the memory used.
+	MaxWidth  int `json:"maxWidth"`
+	MaxHeight int `json:"maxHeight"`
+	// Allowed file extensions for uploads, including the dot.
+	FileTypes []string `json:"fileTypes"`
+	// Maximum number of live threads. The lowest thread on the board is pruned when a new thread goes over the limit.
+	MaxThreads int `json:"maxThreads"`
+	// Number of replies after which a reply no longer bumps its thread.
+	BumpLimit int `json:"bumpLimit"`
+	// Number of threads on each page of the board index.
+	ThreadsPerPage int `json:"threadsPerPage"`
+	// Number of latest replies shown with each thread on the board index. Zero shows none, nil uses the default.
+	PreviewReplies *int `json:"previewReplies"`
+	// Whether metadata such as EXIF is stripped from uploaded JPEGs and PNGs before they are stored.
+	StripMetadata bool `json:"stripMetadata"`
+	// Whether files already posted on the board are rejected.
+	RejectReposts bool `json:"rejectReposts"`
+	// Whether posts are given an ID, the same for every post by a poster in a thread on the same day.
+	PosterIDs bool `json:"posterIDs"`
+	// Whether pruned threads are kept in a read only archive instead of being deleted.
+	Archive bool `json:"archive"`
+}
+
+// DefaultSettings returns the settings used for a board, or any setting a board leaves out of its configuration.
+func DefaultSettings(ID string) Settings {
+	return Settings{
+		Name:           strings.Trim(ID, "/"),
+		MaxFileSize:    10 << 20,
+		MaxWidth:       4096,
+		MaxHeight:      4096,
+		FileTypes:      []string{".png", ".jpeg", ".jpg", ".gif", ".webm"},
+		MaxThreads:     100,
+		BumpLimit:      300,
+		ThreadsPerPage: 10,
+		PreviewReplies: intPtr(5),
+	}
+}
+
+// WithDefaults returns the settings with any unset setting replaced by its default.
+func (s Settings) WithDefaults(ID string) Settings {
+	defaults := DefaultSettings(ID)
+	if s.Name == "" {
+		s.Name = defaults.Name
+	}
+	if s.MaxFileSize <= 0 {
+		s.MaxFileSize = defaults.MaxFileSize
+	}
+	if s.MaxWidth <= 0 {
+		s.MaxWidth = defaults.MaxWidth
+	}
+	if s.MaxHeight <= 0 {
+		s.MaxHeight = defaults.MaxHeight
+	}
+	if len(s.FileTypes) == 0 {
+		s.FileTypes = defaults.FileTypes
+	}
+	if s.MaxThreads <= 0 {
+		s.MaxThreads = defaults.MaxThreads
+	}
+	if s.BumpLimit <= 0 {
+		s.BumpLimit = defaults.BumpLimit
+	}
+	if s.ThreadsPerPage <= 0 {
+		s.ThreadsPerPage = defaults.ThreadsPerPage
+	}
+	if s.PreviewReplies == nil || *s.PreviewReplies < 0 {
+		s.PreviewReplies = defaults.PreviewReplies
+	}
+	return s
+}
+
+func intPtr(i int) *int {
+	return &i
+}
+
+// AllowsFile returns whether the file name has one of the allowed file extensions.
+func (s Settings) AllowsFile(filename string) bool {
+	ext := strings.ToLower(filepath.Ext(filename))
+	for _, allowed := range s.FileTypes {
+		if ext == strings.ToLower(allowed) {
+			return true
+		}
+	}
+	return false
+}
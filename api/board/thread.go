@@ -5,27 +5,32 @@ import (
 	"errors"
 	"github.com/alice-ws/alice/data"
 	"log"
+	"sort"
 	"strconv"
+	"time"
 )
 
 type Store struct {
-	ID      string
-	db      data.KeyValueDB
-	count   data.KeyValueDB
-	threads data.OrderedDB
+	ID         string
+	settings   Settings
+	db         data.KeyValueDB
+	count      data.KeyValueDB
+	threads    data.OrderedDB
+	tripSecret []byte
 }
 
-func NewStore(ID string, db data.KeyValueDB, threads data.OrderedDB) *Store {
+func NewStore(ID string, settings Settings, db data.KeyValueDB, threads data.OrderedDB) *Store {
 	if db == nil {
 		db = data.NewMemoryDB()
 		threads = data.NewMemoryDB()
 	}
 
 	store := &Store{
-		ID:      ID,
-		db:      db,
-		count:   db,
-		threads: threads,
+		ID:       ID,
+		settings: settings.WithDefaults(ID),
+		db:       db,
+		count:    db,
+		threads:  threads,
 	}
 
 	// Set the the board count to 0 if the key does not exist.
@@ -39,11 +44,27 @@ func NewStore(ID string, db data.KeyValueDB, threads data.OrderedDB) *Store {
 	return store
 }
 
+// SetTripSecret sets the secret that keys the secure tripcodes of new posts.
+// It should be set before the board is served and kept the same so posters keep their tripcodes.
+func (store *Store) SetTripSecret(secret []byte) {
+	store.tripSecret = secret
+}
+
+// Settings returns the configuration of the board.
+func (store *Store) Settings() Settings {
+	return store.settings
+}
+
 // Returns key for board count that is stored in the DB
 func boardCountKey(store *Store) string {
 	return store.ID + ":no"
 }
 
+// Returns key for a thread that is stored in the DB, scoped to the board so boards can share a DB.
+func threadKey(store *Store, no string) string {
+	return store.ID + ":thread:" + no
+}
+
 type Thread struct {
 	Post    `json:"post"`
 	Subject string `json:"subject"`
@@ -72,6 +93,11 @@ func (t Thread) String() string {
 	return string(bytes)
 }
 
+// Posts returns the OP followed by the replies.
+func (t Thread) Posts() []Post {
+	return append([]Post{t.Post}, t.Replies...)
+}
+
 // Returns the index and post of the reply with the given post no.
 // Returns -1 if the no is not found in the replies.
 func (t Thread) getReplyWithPostNo(no uint64) (index int, p Post) {
@@ -83,13 +109,31 @@ func (t Thread) getReplyWithPostNo(no uint64) (index int, p Post) {
 	return -1, Post{}
 }
 
+// Returns a copy of the thread with the reply added in post number order.
+// Replies numbered concurrently may be stored out of order, so the reply is inserted rather than appended.
+func (t Thread) withReply(p Post) Thread {
+	i := sort.Search(len(t.Replies), func(i int) bool {
+		return t.Replies[i].No > p.No
+	})
+	replies := make([]Post, 0, len(t.Replies)+1)
+	replies = append(replies, t.Replies[:i]...)
+	replies = append(replies, p)
+	t.Replies = append(replies, t.Replies[i:]...)
+	return t
+}
+
 func (store *Store) AddThread(thread Thread) (uint64, error) {
 	currentNumberOfPosts := store.incrementAndGet()
 
 	// Ignore transformations as the thread is empty. (No cross thread transformations for now)
-	thread.Post, _ = thread.update(currentNumberOfPosts)
-	err := store.db.Set(thread)
-	err = store.threads.SetOrdered(data.NewKeyValuePair(store.ID, strconv.FormatUint(thread.No, 10)), int(thread.Timestamp.Unix()))
+	thread.Post, _ = thread.update(currentNumberOfPosts, store.tripSecret)
+	thread.Post = store.withPosterID(thread.Key(), thread.Post)
+	err := store.db.Set(data.NewKeyValuePair(threadKey(store, thread.Key()), thread.String()))
+	store.refreshCatalog(thread)
+	store.indexFiles(thread.Post)
+	store.indexMedia(thread.Post)
+	store.indexPosters(thread.Key(), thread.Post)
+	err = store.threads.SetOrdered(data.NewKeyValuePair(store.ID, strconv.FormatUint(thread.No, 10)), score(thread.Timestamp))
 	return thread.Post.No, err
 }
 
@@ -97,7 +141,7 @@ func (store *Store) GetAllThreads() ([]Thread, error) {
 	var threads []Thread
 
 	for _, t := range store.threads.GetAllOrderedByScore(store.ID) {
-		threadString, err := store.db.Get(t)
+		threadString, err := store.db.Get(threadKey(store, t))
 		thread, err := newThreadFrom(threadString)
 		if err != nil {
 			return []Thread{}, errors.New("error getting threads")
@@ -110,7 +154,7 @@ func (store *Store) GetAllThreads() ([]Thread, error) {
 }
 
 func (store *Store) GetThread(no string) (Thread, error) {
-	threadString, err := store.db.Get(no)
+	threadString, err := store.db.Get(threadKey(store, no))
 	if err != nil {
 		return Thread{}, errors.New("no such thread found")
 	}
@@ -118,24 +162,87 @@ func (store *Store) GetThread(no string) (Thread, error) {
 }
 
 func (store *Store) AddPost(threadNo string, post Post) (uint64, error) {
-	thread, err := store.GetThread(threadNo)
+	_, err := store.GetThread(threadNo)
 
+	if err != nil && store.isArchived(threadNo) {
+		return 0, ErrArchived
+	}
 	if err != nil {
 		return 0, err
 	}
 
 	currentNumberOfPosts := store.incrementAndGet()
-	post, threadTransformations := post.update(currentNumberOfPosts)
+	post, threadTransformations := post.update(currentNumberOfPosts, store.tripSecret)
+	post = store.withPosterID(threadNo, post)
+
+	// The reply is applied to the latest stored version of the thread so concurrent replies are not lost.
+	var replies int
+	err = store.db.Update(threadKey(store, threadNo), func(current string) (string, error) {
+		thread, err := newThreadFrom(current)
+		if err != nil {
+			return "", err
+		}
 
-	thread.Replies = append(thread.Replies, post)
-	for _, transformation := range threadTransformations {
-		thread = transformation(thread)
+		thread = thread.withReply(post)
+		for _, transformation := range threadTransformations {
+			thread = transformation(thread)
+		}
+		replies = len(thread.Replies)
+		return thread.String(), nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	bumped := store.bumps(post, replies)
+	store.addToCatalog(threadNo, post, bumped)
+	store.indexFiles(post)
+	store.indexMedia(post)
+	store.indexPosters(threadNo, post)
+	if !bumped {
+		return post.No, nil
 	}
-	err = store.db.Set(thread)
-	err = store.threads.SetOrdered(data.NewKeyValuePair(store.ID, strconv.FormatUint(thread.No, 10)), int(post.Timestamp.Unix()))
+	err = store.threads.SetOrdered(data.NewKeyValuePair(store.ID, threadNo), score(post.Timestamp))
 	return post.No, err
 }
 
+// Returns whether the reply moves its thread to the top of the board.
+// Saged replies and replies past the bump limit leave the thread where it is.
+func (store *Store) bumps(reply Post, replies int) bool {
+	if reply.Meta == "sage" || reply.Meta == "nokosage" {
+		return false
+	}
+	return replies <= store.settings.BumpLimit
+}
+
+// Returns the score of a thread bumped at the given time.
+// Nanoseconds are used so threads bumped in the same second keep their order.
+func score(t time.Time) int {
+	return int(t.UnixNano())
+}
+
+// Prune removes the lowest threads on the board until it is within its maximum number of threads.
+// Pruned threads are archived if the board keeps an archive, otherwise they are deleted.
+// The pruned threads are returned so their media can be removed or moved.
+func (store *Store) Prune() ([]Thread, error) {
+	remove := store.DeleteThread
+	if store.settings.Archive {
+		remove = store.ArchiveThread
+	}
+
+	var pruned []Thread
+	threads := store.threads.GetAllOrderedByScore(store.ID)
+	for i := len(threads) - 1; i >= store.settings.MaxThreads; i-- {
+		t, err := remove(threads[i])
+		if err != nil {
+			return pruned, err
+		}
+		log.Printf("Pruned thread %s from board %s", threads[i], store.ID)
+		pruned = append(pruned, t)
+	}
+	return pruned, nil
+}
+
 func (store *Store) incrementAndGet() uint64 {
 	currentCount, err := store.count.Increment(boardCountKey(store))
 	if err != nil {
@@ -0,0 +1,149 @@
+package board
+
+import (
+	"github.com/alice-ws/alice/data"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestStore_AddPost_concurrentRepliesAreNotLost(t *testing.T) {
+	const replies = 100
+	store := NewStore("/test/", Settings{}, data.NewMemoryDB(), data.NewMemoryDB())
+
+	threadNo, err := store.AddThread(thread())
+	if err != nil {
+		t.Fatalf("could not add thread: %v", err)
+	}
+	op := strconv.FormatUint(threadNo, 10)
+
+	var wg sync.WaitGroup
+	for i := 0; i < replies; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := store.AddPost(op, post().with("Comment", ">>"+op)); err != nil {
+				t.Errorf("could not add post: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	got, err := store.GetThread(op)
+	if err != nil {
+		t.Fatalf("could not get thread: %v", err)
+	}
+	if len(got.Replies) != replies {
+		t.Errorf("expected %d replies, got %d", replies, len(got.Replies))
+	}
+	if len(got.QuotedBy) != replies {
+		t.Errorf("expected OP to be quoted by %d replies, got %d", replies, len(got.QuotedBy))
+	}
+	for i := 1; i < len(got.Replies); i++ {
+		if got.Replies[i-1].No >= got.Replies[i].No {
+			t.Errorf("replies are not in post number order: %d before %d", got.Replies[i-1].No, got.Replies[i].No)
+		}
+	}
+}
+
+func TestStore_GetAllThreads_bumpedThreadIsListedOnce(t *testing.T) {
+	store := NewStore("/test/", Settings{}, data.NewMemoryDB(), data.NewMemoryDB())
+
+	first, _ := store.AddThread(thread())
+	_, _ = store.AddThread(thread())
+	_, _ = store.AddPost(strconv.FormatUint(first, 10), post())
+	_, _ = store.AddPost(strconv.FormatUint(first, 10), post())
+
+	threads, err := store.GetAllThreads()
+	if err != nil {
+		t.Fatalf("could not get threads: %v", err)
+	}
+	if len(threads) != 2 {
+		t.Errorf("expected 2 threads, got %d", len(threads))
+	}
+}
+
+func TestStore_boardsSharingADBDoNotOverwriteThreads(t *testing.T) {
+	db := data.NewMemoryDB()
+	obj := NewStore("/obj/", Settings{}, db, db)
+	a := NewStore("/a/", Settings{}, db, db)
+
+	objNo, _ := obj.AddThread(NewThread(post(), "obj"))
+	aNo, _ := a.AddThread(NewThread(post(), "a"))
+
+	if objNo != aNo {
+		t.Fatalf("expected both boards to start at the same post number, got %d and %d", objNo, aNo)
+	}
+	no := strconv.FormatUint(objNo, 10)
+	if got, _ := obj.GetThread(no); got.Subject != "obj" {
+		t.Errorf("/obj/ thread subject = %s, want obj", got.Subject)
+	}
+	if got, _ := a.GetThread(no); got.Subject != "a" {
+		t.Errorf("/a/ thread subject = %s, want a", got.Subject)
+	}
+}
+
+func TestStore_Prune(t *testing.T) {
+	store := NewStore("/test/", Settings{MaxThreads: 2}, data.NewMemoryDB(), data.NewMemoryDB())
+	oldest, _ := store.AddThread(thread())
+	_, _ = store.AddThread(thread())
+	_, _ = store.AddThread(thread())
+
+	pruned, err := store.Prune()
+
+	if err != nil || len(pruned) != 1 || pruned[0].No != oldest {
+		t.Errorf("Prune() = %v, %v, want thread %d", pruned, err, oldest)
+	}
+	if threads, _ := store.GetAllThreads(); len(threads) != 2 {
+		t.Errorf("expected 2 threads left, got %d", len(threads))
+	}
+	if _, err := store.GetThread(strconv.FormatUint(oldest, 10)); err == nil {
+		t.Errorf("expected pruned thread to be deleted")
+	}
+}
+
+func TestStore_Prune_withinLimit(t *testing.T) {
+	store := NewStore("/test/", Settings{MaxThreads: 2}, data.NewMemoryDB(), data.NewMemoryDB())
+	_, _ = store.AddThread(thread())
+	_, _ = store.AddThread(thread())
+
+	if pruned, err := store.Prune(); err != nil || len(pruned) != 0 {
+		t.Errorf("Prune() = %v, %v, want nothing pruned", pruned, err)
+	}
+}
+
+func TestStore_GetAllThreads_bumpOrder(t *testing.T) {
+	tests := []struct {
+		name      string
+		bumpLimit int
+		reply     Post
+		replies   int
+		wantFirst int
+	}{
+		{name: "reply bumps thread", reply: post(), replies: 1, wantFirst: 0},
+		{name: "noko reply bumps thread", reply: post().with("Email", "noko"), replies: 1, wantFirst: 0},
+		{name: "sage reply does not bump thread", reply: post().with("Email", "sage"), replies: 1, wantFirst: 1},
+		{name: "nokosage reply does not bump thread", reply: post().with("Email", "nokosage"), replies: 1, wantFirst: 1},
+		{name: "reply within bump limit bumps thread", bumpLimit: 2, reply: post(), replies: 2, wantFirst: 0},
+		{name: "reply past bump limit does not bump thread", bumpLimit: 2, reply: post(), replies: 3, wantFirst: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewStore("/test/", Settings{BumpLimit: tt.bumpLimit}, data.NewMemoryDB(), data.NewMemoryDB())
+			first, _ := store.AddThread(thread())
+			second, _ := store.AddThread(thread())
+			nos := []uint64{first, second}
+
+			// Every reply but the last is saged so only the last reply can bump the first thread.
+			for i := 1; i < tt.replies; i++ {
+				_, _ = store.AddPost(strconv.FormatUint(first, 10), post().with("Email", "sage"))
+			}
+			_, _ = store.AddPost(strconv.FormatUint(first, 10), tt.reply)
+
+			threads, _ := store.GetAllThreads()
+			if len(threads) != 2 || threads[0].No != nos[tt.wantFirst] {
+				t.Errorf("expected thread %d at the top of the board, got %v", nos[tt.wantFirst], threads)
+			}
+		})
+	}
+}
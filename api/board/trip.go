@@ -0,0 +1,37 @@
+package board
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"github.com/aquilax/tripcode"
+	"strings"
+)
+
+// secureTripLength is the number of characters of a secure tripcode after the "!!".
+const secureTripLength = 11
+
+// Returns the name without its password, and the tripcode of the password if the name has one.
+// "name#password" gives a classic tripcode, matching the tripcodes of other imageboards for the same password.
+// "name##password" gives a secure tripcode, keyed with the secret so it cannot be cracked without it.
+func splitTrip(name string, secret []byte) (string, string) {
+	i := strings.Index(name, "#")
+	if i < 0 {
+		return name, ""
+	}
+	name, password := name[:i], name[i+1:]
+
+	if strings.HasPrefix(password, "#") {
+		if password = password[1:]; password == "" {
+			return name, ""
+		}
+		mac := hmac.New(sha256.New, secret)
+		_, _ = mac.Write([]byte(password))
+		return name, "!!" + base64.StdEncoding.EncodeToString(mac.Sum(nil))[:secureTripLength]
+	}
+
+	if trip := tripcode.Tripcode(password); trip != "" {
+		return name, "!" + trip
+	}
+	return name, ""
+}
@@ -0,0 +1,55 @@
+package board
+
+import (
+	"testing"
+)
+
+func Test_splitTrip(t *testing.T) {
+	tests := []struct {
+		name     string
+		wantName string
+		wantTrip string
+	}{
+		{"Anonymous", "Anonymous", ""},
+		{"name#password", "name", "!ozOtJW9BFA"},
+		{"#tea", "", "!WokonZwxw2"},
+		{"name#", "name", ""},
+		{"name##", "name", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name, trip := splitTrip(tt.name, []byte("secret"))
+			if name != tt.wantName || trip != tt.wantTrip {
+				t.Errorf("splitTrip(%s) = %s, %s, want %s, %s", tt.name, name, trip, tt.wantName, tt.wantTrip)
+			}
+		})
+	}
+}
+
+func Test_splitTrip_secure(t *testing.T) {
+	name, trip := splitTrip("name##password", []byte("secret"))
+	if name != "name" || len(trip) != 2+secureTripLength || trip[:2] != "!!" {
+		t.Errorf("splitTrip() = %s, %s, want name and a secure tripcode", name, trip)
+	}
+	if _, again := splitTrip("other##password", []byte("secret")); again != trip {
+		t.Errorf("expected the same password to give the same tripcode, got %s and %s", trip, again)
+	}
+	if _, other := splitTrip("name##password", []byte("other secret")); other == trip {
+		t.Errorf("expected a different secret to give a different tripcode")
+	}
+	if _, classic := splitTrip("name#password", []byte("secret")); classic == trip {
+		t.Errorf("expected the secure tripcode to differ from the classic tripcode")
+	}
+}
+
+func TestStore_AddPost_trip(t *testing.T) {
+	store := NewStore("/test/", Settings{}, nil, nil)
+	store.SetTripSecret([]byte("secret"))
+	_, _ = store.AddThread(NewThread(post().with("Name", "#password"), "A subject"))
+
+	thread, _ := store.GetThread("0")
+
+	if thread.Name != "Anonymous" || thread.Trip != "!ozOtJW9BFA" {
+		t.Errorf("thread posted by %s %s, want Anonymous !ozOtJW9BFA", thread.Name, thread.Trip)
+	}
+}
@@ -1,5 +1,7 @@
 package data
 
+import "time"
+
 type DB interface {
 	KeyValueDB
 	OrderedDB
@@ -12,10 +14,21 @@ type KeyValueDB interface {
 	Increment(string) (int64, error)
 	Get(string) (string, error)
 	Remove(string) error
+	// Update atomically replaces the value of an existing key with the value returned by the update function.
+	// The update function may be called more than once if the key is modified concurrently.
+	Update(string, func(string) (string, error)) error
+	// SetIfAbsent sets the key only if it is not already set, returning whether it was set.
+	// The key expires after the expiry, or never if the expiry is 0, like Redis' SET NX PX.
+	SetIfAbsent(kv KeyValue, expiry time.Duration) (bool, error)
 }
 
 type OrderedDB interface {
 	SetOrdered(KeyValue, int) error
 	GetAllOrderedByScore(string) []string
+	// GetRangeByRank returns the members ranked from start to stop inclusive, from highest to lowest score.
+	// Ranks start at 0 and negative ranks count back from the lowest score, like Redis' ZREVRANGE.
+	GetRangeByRank(key string, start, stop int64) []string
+	// CountOrdered returns the number of members in the ordered set.
+	CountOrdered(string) int64
 	RemoveOrdered(kv KeyValue) error
 }
@@ -0,0 +1,80 @@
+package dbtest
+
+import (
+	"github.com/alice-ws/alice/data"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+const workers = 50
+
+// TestConcurrency checks no writes are lost when the DB is used from many goroutines at once.
+func TestConcurrency(t *testing.T, newDB NewDB) {
+	t.Run("increments", func(t *testing.T) {
+		db := newDB()
+		parallel(func(i int) {
+			if _, err := db.Increment("k"); err != nil {
+				t.Errorf("Increment() error = %v", err)
+			}
+		})
+		expectValue(t, db, "k", strconv.Itoa(workers))
+	})
+
+	t.Run("updates", func(t *testing.T) {
+		db := newDB()
+		_ = db.Set(data.NewKeyValuePair("k", "0"))
+		parallel(func(i int) {
+			err := db.Update("k", func(current string) (string, error) {
+				n, err := strconv.Atoi(current)
+				return strconv.Itoa(n + 1), err
+			})
+			if err != nil {
+				t.Errorf("Update() error = %v", err)
+			}
+		})
+		expectValue(t, db, "k", strconv.Itoa(workers))
+	})
+
+	t.Run("sets, gets and removes", func(t *testing.T) {
+		db := newDB()
+		parallel(func(i int) {
+			k := "k" + strconv.Itoa(i)
+			_ = db.Set(data.NewKeyValuePair(k, strconv.Itoa(i)))
+			if got, err := db.Get(k); err != nil || got != strconv.Itoa(i) {
+				t.Errorf("Get(%s) = %s, %v, want %d", k, got, err, i)
+			}
+			_ = db.Remove(k)
+		})
+		for i := 0; i < workers; i++ {
+			expectMissing(t, db, "k"+strconv.Itoa(i))
+		}
+	})
+
+	t.Run("adds ordered members", func(t *testing.T) {
+		db := newDB()
+		parallel(func(i int) {
+			setOrdered(db, strconv.Itoa(i), i)
+			_ = db.GetAllOrderedByScore(key)
+		})
+		if got := db.GetAllOrderedByScore(key); len(got) != workers {
+			t.Errorf("expected %d members, got %d", workers, len(got))
+		}
+	})
+}
+
+// Runs the function on every worker at the same time and waits for all of them to finish.
+func parallel(f func(i int)) {
+	var start, done sync.WaitGroup
+	start.Add(1)
+	for i := 0; i < workers; i++ {
+		done.Add(1)
+		go func(i int) {
+			defer done.Done()
+			start.Wait()
+			f(i)
+		}(i)
+	}
+	start.Done()
+	done.Wait()
+}
@@ -0,0 +1,18 @@
+// Package dbtest provides conformance tests that every data.DB implementation should pass,
+// so that the in memory DB behaves the same as Redis.
+package dbtest
+
+import (
+	"github.com/alice-ws/alice/data"
+	"testing"
+)
+
+// NewDB returns an empty DB to run a conformance test against.
+type NewDB func() data.DB
+
+// Run runs every conformance test against the DB.
+func Run(t *testing.T, newDB NewDB) {
+	t.Run("KeyValueDB", func(t *testing.T) { TestKeyValueDB(t, newDB) })
+	t.Run("OrderedDB", func(t *testing.T) { TestOrderedDB(t, newDB) })
+	t.Run("Concurrency", func(t *testing.T) { TestConcurrency(t, newDB) })
+}
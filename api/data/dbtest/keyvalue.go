@@ -0,0 +1,142 @@
+package dbtest
+
+import (
+	"errors"
+	"github.com/alice-ws/alice/data"
+	"testing"
+	"time"
+)
+
+// TestKeyValueDB checks Set, Get, Remove, Increment, Update and SetIfAbsent behave as they do in Redis.
+func TestKeyValueDB(t *testing.T, newDB NewDB) {
+	t.Run("pings", func(t *testing.T) {
+		if !newDB().Ping() {
+			t.Errorf("Ping() = false, want true")
+		}
+	})
+
+	t.Run("gets a set value", func(t *testing.T) {
+		db := newDB()
+		_ = db.Set(data.NewKeyValuePair("k", "v"))
+		expectValue(t, db, "k", "v")
+	})
+
+	t.Run("overwrites an existing value", func(t *testing.T) {
+		db := newDB()
+		_ = db.Set(data.NewKeyValuePair("k", "v"))
+		_ = db.Set(data.NewKeyValuePair("k", "v2"))
+		expectValue(t, db, "k", "v2")
+	})
+
+	t.Run("errors getting a missing key", func(t *testing.T) {
+		expectMissing(t, newDB(), "k")
+	})
+
+	t.Run("removes a key", func(t *testing.T) {
+		db := newDB()
+		_ = db.Set(data.NewKeyValuePair("k", "v"))
+		if err := db.Remove("k"); err != nil {
+			t.Errorf("Remove() error = %v", err)
+		}
+		expectMissing(t, db, "k")
+	})
+
+	t.Run("ignores removing a missing key", func(t *testing.T) {
+		if err := newDB().Remove("k"); err != nil {
+			t.Errorf("Remove() error = %v, want nil", err)
+		}
+	})
+
+	t.Run("increments and gets a number", func(t *testing.T) {
+		db := newDB()
+		_ = db.Set(data.NewKeyValuePair("k", "41"))
+		if got, err := db.Increment("k"); err != nil || got != 42 {
+			t.Errorf("Increment() = %d, %v, want 42", got, err)
+		}
+		expectValue(t, db, "k", "42")
+	})
+
+	t.Run("increments a missing key from 0", func(t *testing.T) {
+		db := newDB()
+		if got, err := db.Increment("k"); err != nil || got != 1 {
+			t.Errorf("Increment() = %d, %v, want 1", got, err)
+		}
+		expectValue(t, db, "k", "1")
+	})
+
+	t.Run("errors incrementing a value that is not a number", func(t *testing.T) {
+		db := newDB()
+		_ = db.Set(data.NewKeyValuePair("k", "v"))
+		if _, err := db.Increment("k"); err == nil {
+			t.Errorf("Increment() error = nil, want error")
+		}
+		expectValue(t, db, "k", "v")
+	})
+
+	t.Run("updates an existing value", func(t *testing.T) {
+		db := newDB()
+		_ = db.Set(data.NewKeyValuePair("k", "v"))
+		err := db.Update("k", func(current string) (string, error) {
+			return current + "2", nil
+		})
+		if err != nil {
+			t.Errorf("Update() error = %v", err)
+		}
+		expectValue(t, db, "k", "v2")
+	})
+
+	t.Run("errors updating a missing key", func(t *testing.T) {
+		db := newDB()
+		err := db.Update("k", func(current string) (string, error) {
+			return "v", nil
+		})
+		if err == nil {
+			t.Errorf("Update() error = nil, want error")
+		}
+		expectMissing(t, db, "k")
+	})
+
+	t.Run("keeps the value when the update fails", func(t *testing.T) {
+		db := newDB()
+		_ = db.Set(data.NewKeyValuePair("k", "v"))
+		updateErr := errors.New("update failed")
+		err := db.Update("k", func(current string) (string, error) {
+			return "v2", updateErr
+		})
+		if err != updateErr {
+			t.Errorf("Update() error = %v, want %v", err, updateErr)
+		}
+		expectValue(t, db, "k", "v")
+	})
+
+	t.Run("sets an absent key", func(t *testing.T) {
+		db := newDB()
+		if set, err := db.SetIfAbsent(data.NewKeyValuePair("k", "v"), time.Minute); err != nil || !set {
+			t.Errorf("SetIfAbsent() = %t, %v, want true", set, err)
+		}
+		expectValue(t, db, "k", "v")
+	})
+
+	t.Run("does not set a key that is already set", func(t *testing.T) {
+		db := newDB()
+		_ = db.Set(data.NewKeyValuePair("k", "v"))
+		if set, err := db.SetIfAbsent(data.NewKeyValuePair("k", "v2"), 0); err != nil || set {
+			t.Errorf("SetIfAbsent() = %t, %v, want false", set, err)
+		}
+		expectValue(t, db, "k", "v")
+	})
+}
+
+func expectValue(t *testing.T, db data.DB, key, want string) {
+	t.Helper()
+	if got, err := db.Get(key); err != nil || got != want {
+		t.Errorf("Get(%s) = %s, %v, want %s", key, got, err, want)
+	}
+}
+
+func expectMissing(t *testing.T, db data.DB, key string) {
+	t.Helper()
+	if got, err := db.Get(key); err == nil {
+		t.Errorf("Get(%s) = %s, want error", key, got)
+	}
+}
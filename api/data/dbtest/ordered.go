@@ -0,0 +1,151 @@
+package dbtest
+
+import (
+	"github.com/alice-ws/alice/data"
+	"reflect"
+	"testing"
+)
+
+// TestOrderedDB checks the DB behaves as a Redis sorted set.
+func TestOrderedDB(t *testing.T, newDB NewDB) {
+	tests := []struct {
+		name  string
+		setup func(db data.DB)
+		want  []string
+	}{
+		{
+			name:  "returns nothing for a missing key",
+			setup: func(db data.DB) {},
+			want:  []string{},
+		},
+		{
+			name: "returns members from highest to lowest score",
+			setup: func(db data.DB) {
+				setOrdered(db, "a", 1)
+				setOrdered(db, "c", 3)
+				setOrdered(db, "b", 2)
+			},
+			want: []string{"c", "b", "a"},
+		},
+		{
+			name: "returns members with the same score in reverse lexicographical order",
+			setup: func(db data.DB) {
+				setOrdered(db, "a", 1)
+				setOrdered(db, "c", 1)
+				setOrdered(db, "b", 1)
+			},
+			want: []string{"c", "b", "a"},
+		},
+		{
+			name: "updates the score of an existing member instead of adding it again",
+			setup: func(db data.DB) {
+				setOrdered(db, "a", 1)
+				setOrdered(db, "b", 2)
+				setOrdered(db, "a", 3)
+			},
+			want: []string{"a", "b"},
+		},
+		{
+			name: "removes a single member",
+			setup: func(db data.DB) {
+				setOrdered(db, "a", 1)
+				setOrdered(db, "b", 2)
+				setOrdered(db, "c", 3)
+				_ = db.RemoveOrdered(data.NewKeyValuePair(key, "b"))
+			},
+			want: []string{"c", "a"},
+		},
+		{
+			name: "ignores removing a missing member",
+			setup: func(db data.DB) {
+				setOrdered(db, "a", 1)
+				_ = db.RemoveOrdered(data.NewKeyValuePair(key, "b"))
+			},
+			want: []string{"a"},
+		},
+		{
+			name: "removing the last member empties the set",
+			setup: func(db data.DB) {
+				setOrdered(db, "a", 1)
+				_ = db.RemoveOrdered(data.NewKeyValuePair(key, "a"))
+			},
+			want: []string{},
+		},
+		{
+			name: "keeps sets with different keys apart",
+			setup: func(db data.DB) {
+				setOrdered(db, "a", 1)
+				_ = db.SetOrdered(data.NewKeyValuePair(key+"other", "b"), 2)
+			},
+			want: []string{"a"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newDB()
+			tt.setup(db)
+			if got := db.GetAllOrderedByScore(key); !equal(got, tt.want) {
+				t.Errorf("GetAllOrderedByScore() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	ranges := []struct {
+		name        string
+		start, stop int64
+		want        []string
+	}{
+		{name: "first members", start: 0, stop: 1, want: []string{"e", "d"}},
+		{name: "middle members", start: 1, stop: 3, want: []string{"d", "c", "b"}},
+		{name: "single member", start: 2, stop: 2, want: []string{"c"}},
+		{name: "range past the end", start: 3, stop: 10, want: []string{"b", "a"}},
+		{name: "range after the end", start: 5, stop: 10, want: []string{}},
+		{name: "all members with negative stop", start: 0, stop: -1, want: []string{"e", "d", "c", "b", "a"}},
+		{name: "last members with negative ranks", start: -2, stop: -1, want: []string{"b", "a"}},
+		{name: "start after stop", start: 3, stop: 1, want: []string{}},
+	}
+	for _, tt := range ranges {
+		t.Run("gets range of "+tt.name, func(t *testing.T) {
+			db := newDB()
+			for i, value := range []string{"a", "b", "c", "d", "e"} {
+				setOrdered(db, value, i)
+			}
+			if got := db.GetRangeByRank(key, tt.start, tt.stop); !equal(got, tt.want) {
+				t.Errorf("GetRangeByRank(%d, %d) = %v, want %v", tt.start, tt.stop, got, tt.want)
+			}
+		})
+	}
+
+	t.Run("gets empty range for a missing key", func(t *testing.T) {
+		if got := newDB().GetRangeByRank(key, 0, -1); len(got) != 0 {
+			t.Errorf("GetRangeByRank() = %v, want nothing", got)
+		}
+	})
+
+	t.Run("counts members", func(t *testing.T) {
+		db := newDB()
+		if got := db.CountOrdered(key); got != 0 {
+			t.Errorf("CountOrdered() = %d for missing key, want 0", got)
+		}
+		setOrdered(db, "a", 1)
+		setOrdered(db, "b", 2)
+		setOrdered(db, "a", 3)
+		if got := db.CountOrdered(key); got != 2 {
+			t.Errorf("CountOrdered() = %d, want 2", got)
+		}
+	})
+}
+
+const key = "/dbtest/"
+
+func setOrdered(db data.DB, value string, score int) {
+	_ = db.SetOrdered(data.NewKeyValuePair(key, value), score)
+}
+
+// Compares string slices treating nil and empty as equal
+func equal(got, want []string) bool {
+	if len(got) == 0 && len(want) == 0 {
+		return true
+	}
+	return reflect.DeepEqual(got, want)
+}
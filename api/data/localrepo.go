@@ -4,6 +4,7 @@ import (
 	"io"
 	"io/ioutil"
 	"log"
+	"mime"
 	"os"
 	"path"
 	"path/filepath"
@@ -24,23 +25,78 @@ func NewLocalRepo(dir string) MediaRepo {
 	return LocalRepo{dir: dir}
 }
 
+// Store writes the file with the name in the directory. The name is the file's URI.
 func (r LocalRepo) Store(fileReader io.Reader, _ string, name string, _ int64) (string, error) {
-
-	tempImage, err := ioutil.TempFile(r.dir, "*-"+name)
 	inMemoryImage, err := ioutil.ReadAll(fileReader)
 	if err != nil {
 		return "", err
 	}
 
-	_, err = tempImage.Write(inMemoryImage)
+	URI := r.URI("", name)
+	err = ioutil.WriteFile(r.path(URI), inMemoryImage, 0644)
 	if err != nil {
 		return "", err
 	}
+	return URI, nil
+}
 
-	return filepath.Base(tempImage.Name()), nil
+// URI returns the name as all groups share the directory.
+func (r LocalRepo) URI(_ string, name string) string {
+	return filepath.Base(name)
 }
 
 func (r LocalRepo) GenerateUniqueName(fileName string) string {
 	ext := path.Ext(fileName)
 	return strconv.FormatInt(time.Now().UnixNano(), 10) + ext
 }
+
+func (r LocalRepo) Delete(URI string) error {
+	return os.Remove(r.path(URI))
+}
+
+func (r LocalRepo) Exists(URI string) (bool, error) {
+	_, err := os.Stat(r.path(URI))
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (r LocalRepo) Stat(URI string) (MediaInfo, error) {
+	info, err := os.Stat(r.path(URI))
+	if err != nil {
+		return MediaInfo{}, err
+	}
+	return MediaInfo{
+		URI:         URI,
+		Size:        info.Size(),
+		ContentType: mime.TypeByExtension(filepath.Ext(URI)),
+		Modified:    info.ModTime(),
+	}, nil
+}
+
+// List returns every file in the directory as all groups share the directory.
+func (r LocalRepo) List(_ string) ([]string, error) {
+	files, err := ioutil.ReadDir(r.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	URIs := make([]string, 0, len(files))
+	for _, f := range files {
+		if !f.IsDir() {
+			URIs = append(URIs, f.Name())
+		}
+	}
+	return URIs, nil
+}
+
+// Move leaves the file where it is as all groups share the directory.
+func (r LocalRepo) Move(URI string, _ string) (string, error) {
+	return URI, nil
+}
+
+// Returns the path of the file with the URI, which is the file name returned by Store.
+func (r LocalRepo) path(URI string) string {
+	return filepath.Join(r.dir, filepath.Base(URI))
+}
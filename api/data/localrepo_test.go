@@ -0,0 +1,37 @@
+package data
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLocalRepo_StoreStatListAndDelete(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "alice")
+	defer os.RemoveAll(dir)
+	repo := NewLocalRepo(dir)
+
+	URI, err := repo.Store(strings.NewReader("image"), "images", "0.png", 5)
+	if err != nil {
+		t.Fatalf("Store() error = %v", err)
+	}
+
+	if exists, err := repo.Exists(URI); !exists || err != nil {
+		t.Errorf("Exists() = %v, %v, want true", exists, err)
+	}
+	if info, err := repo.Stat(URI); err != nil || info.Size != 5 || info.ContentType != "image/png" {
+		t.Errorf("Stat() = %v, %v, want 5 byte image/png", info, err)
+	}
+	if URIs, err := repo.List("images"); err != nil || !reflect.DeepEqual(URIs, []string{URI}) {
+		t.Errorf("List() = %v, %v, want %v", URIs, err, []string{URI})
+	}
+
+	if err := repo.Delete(URI); err != nil {
+		t.Errorf("Delete() error = %v", err)
+	}
+	if exists, err := repo.Exists(URI); exists || err != nil {
+		t.Errorf("Exists() = %v, %v, want false after delete", exists, err)
+	}
+}
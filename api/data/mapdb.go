@@ -4,13 +4,27 @@ import (
 	"errors"
 	"sort"
 	"strconv"
+	"sync"
+	"time"
 )
 
+// MemoryDB is an in memory DB used when no other DB is available. It is safe for concurrent use.
 type MemoryDB struct {
+	mu      sync.RWMutex
 	m       map[string]string
-	ordered map[string]list
+	expires map[string]time.Time
+	ordered map[string]sortedSet
+	// Number of keys with an expiry at which expired keys are next removed
+	sweepAt int
 }
 
+// Expired keys are removed once this many keys have an expiry, then each time the number of keys with an expiry doubles,
+// so removing them takes constant time on average.
+const minSweep = 64
+
+// A sorted set of unique members and their scores
+type sortedSet map[string]int
+
 type member struct {
 	value string
 	score int
@@ -19,7 +33,7 @@ type member struct {
 type list []member
 
 func NewMemoryDB() *MemoryDB {
-	return &MemoryDB{m: make(map[string]string), ordered: make(map[string]list)}
+	return &MemoryDB{m: make(map[string]string), expires: make(map[string]time.Time), ordered: make(map[string]sortedSet), sweepAt: minSweep}
 }
 
 func (*MemoryDB) Ping() bool {
@@ -27,12 +41,23 @@ func (*MemoryDB) Ping() bool {
 }
 
 func (db *MemoryDB) Set(u KeyValue) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
 	db.m[u.Key()] = u.String()
+	delete(db.expires, u.Key())
 	return nil
 }
 
 func (db *MemoryDB) Increment(key string) (int64, error) {
-	i, err := strconv.ParseInt(db.m[key], 10, 0)
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	// Like Redis, a missing key is incremented from 0
+	val, ok := db.get(key)
+	if !ok {
+		val = "0"
+		delete(db.expires, key)
+	}
+	i, err := strconv.ParseInt(val, 10, 0)
 	if err != nil {
 		return 0, err
 	}
@@ -43,43 +68,169 @@ func (db *MemoryDB) Increment(key string) (int64, error) {
 }
 
 func (db *MemoryDB) Get(key string) (string, error) {
-	if val, ok := db.m[key]; ok {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	if val, ok := db.get(key); ok {
 		return val, nil
 	}
 	return "", errors.New("key does not exist")
 }
 
 func (db *MemoryDB) Remove(u string) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
 	delete(db.m, u)
+	delete(db.expires, u)
+	return nil
+}
+
+func (db *MemoryDB) Update(key string, update func(string) (string, error)) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	val, ok := db.get(key)
+	if !ok {
+		return errors.New("key does not exist")
+	}
+
+	updated, err := update(val)
+	if err != nil {
+		return err
+	}
+	// Like Redis' SET, replacing the value removes any expiry
+	db.m[key] = updated
+	delete(db.expires, key)
 	return nil
 }
 
+func (db *MemoryDB) SetIfAbsent(kv KeyValue, expiry time.Duration) (bool, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if _, ok := db.get(kv.Key()); ok {
+		return false, nil
+	}
+
+	db.m[kv.Key()] = kv.String()
+	delete(db.expires, kv.Key())
+	if expiry > 0 {
+		db.expires[kv.Key()] = time.Now().Add(expiry)
+		if len(db.expires) >= db.sweepAt {
+			db.sweep()
+		}
+	}
+	return true, nil
+}
+
+// Removes expired keys. Callers must hold the write lock.
+func (db *MemoryDB) sweep() {
+	now := time.Now()
+	for key, expires := range db.expires {
+		if !now.Before(expires) {
+			delete(db.m, key)
+			delete(db.expires, key)
+		}
+	}
+	db.sweepAt = 2 * len(db.expires)
+	if db.sweepAt < minSweep {
+		db.sweepAt = minSweep
+	}
+}
+
+// Returns the value of the key if it is set and has not expired. Callers must hold the lock.
+// Expired keys are left for SetIfAbsent to remove, so readers only need the read lock.
+func (db *MemoryDB) get(key string) (string, bool) {
+	if expires, ok := db.expires[key]; ok && !time.Now().Before(expires) {
+		return "", false
+	}
+	val, ok := db.m[key]
+	return val, ok
+}
+
+// SetOrdered adds the member to the sorted set or updates its score if it is already a member.
 func (db *MemoryDB) SetOrdered(kv KeyValue, score int) error {
-	m := member{kv.String(), score}
-	if val, ok := db.ordered[kv.Key()]; ok {
-		val = append(val, m)
-		db.ordered[kv.Key()] = val
-	} else {
-		db.ordered[kv.Key()] = []member{m}
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	set, ok := db.ordered[kv.Key()]
+	if !ok {
+		set = make(sortedSet)
+		db.ordered[kv.Key()] = set
 	}
+	set[kv.String()] = score
 	return nil
 }
 
+// GetAllOrderedByScore returns the members from highest to lowest score.
+// Members with the same score are in reverse lexicographical order like Redis' ZREVRANGEBYSCORE.
 func (db *MemoryDB) GetAllOrderedByScore(key string) []string {
-	if val, ok := db.ordered[key]; ok {
-		sort.Slice(val, func(i, j int) bool {
-			return val[i].score < val[j].score
-		})
-		return val.values()
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	if set, ok := db.ordered[key]; ok {
+		return set.sorted().values()
 	}
 	return nil
 }
 
+// GetRangeByRank returns the members ranked from start to stop inclusive, from highest to lowest score.
+// Negative ranks count back from the lowest score like Redis' ZREVRANGE, and ranks past the end are ignored.
+func (db *MemoryDB) GetRangeByRank(key string, start, stop int64) []string {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	set, ok := db.ordered[key]
+	if !ok {
+		return nil
+	}
+
+	size := int64(len(set))
+	if start < 0 {
+		start += size
+	}
+	if stop < 0 {
+		stop += size
+	}
+	if start < 0 {
+		start = 0
+	}
+	if stop >= size {
+		stop = size - 1
+	}
+	if start > stop {
+		return []string{}
+	}
+	return set.sorted()[start : stop+1].values()
+}
+
+func (db *MemoryDB) CountOrdered(key string) int64 {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	return int64(len(db.ordered[key]))
+}
+
+// RemoveOrdered removes the member from the sorted set, deleting the set once it is empty.
 func (db *MemoryDB) RemoveOrdered(kv KeyValue) error {
-	delete(db.ordered, kv.Key())
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if set, ok := db.ordered[kv.Key()]; ok {
+		delete(set, kv.String())
+		if len(set) == 0 {
+			delete(db.ordered, kv.Key())
+		}
+	}
 	return nil
 }
 
+func (s sortedSet) sorted() list {
+	l := make(list, 0, len(s))
+	for value, score := range s {
+		l = append(l, member{value, score})
+	}
+	sort.Slice(l, func(i, j int) bool {
+		if l[i].score == l[j].score {
+			return l[i].value > l[j].value
+		}
+		return l[i].score > l[j].score
+	})
+	return l
+}
+
 func (l list) values() []string {
 	strings := make([]string, 0)
 	for _, v := range l {
@@ -0,0 +1,13 @@
+package data_test
+
+import (
+	"github.com/alice-ws/alice/data"
+	"github.com/alice-ws/alice/data/dbtest"
+	"testing"
+)
+
+func TestMemoryDB_conformance(t *testing.T) {
+	dbtest.Run(t, func() data.DB {
+		return data.NewMemoryDB()
+	})
+}
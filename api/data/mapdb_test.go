@@ -0,0 +1,46 @@
+package data
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestMemoryDB_SetIfAbsent_expires(t *testing.T) {
+	db := NewMemoryDB()
+	_, _ = db.SetIfAbsent(NewKeyValuePair("k", "v"), 10*time.Millisecond)
+
+	if set, _ := db.SetIfAbsent(NewKeyValuePair("k", "v2"), time.Minute); set {
+		t.Errorf("SetIfAbsent() set a key before it expired")
+	}
+	time.Sleep(20 * time.Millisecond)
+
+	if _, err := db.Get("k"); err == nil {
+		t.Errorf("Get() found an expired key")
+	}
+	if n, err := db.Increment("k"); err != nil || n != 1 {
+		t.Errorf("Increment() = %d, %v, want an expired key to be incremented from 0", n, err)
+	}
+	if got, err := db.Get("k"); err != nil || got != "1" {
+		t.Errorf("Get() = %s, %v, want the incremented key to no longer expire", got, err)
+	}
+}
+
+func TestMemoryDB_SetIfAbsent_removesExpiredKeys(t *testing.T) {
+	db := NewMemoryDB()
+	for i := 0; i < minSweep-1; i++ {
+		_, _ = db.SetIfAbsent(NewKeyValuePair("expired"+strconv.Itoa(i), "v"), time.Millisecond)
+	}
+	time.Sleep(10 * time.Millisecond)
+
+	for i := 0; i < 10*minSweep; i++ {
+		_, _ = db.SetIfAbsent(NewKeyValuePair("k"+strconv.Itoa(i), "v"), time.Minute)
+	}
+
+	if _, ok := db.m["expired0"]; ok {
+		t.Errorf("expected expired keys to be removed")
+	}
+	if len(db.m) != 10*minSweep || len(db.expires) != 10*minSweep {
+		t.Errorf("got %d keys and %d expiries, want %d of each", len(db.m), len(db.expires), 10*minSweep)
+	}
+}
@@ -2,9 +2,27 @@ package data
 
 import (
 	"io"
+	"time"
 )
 
 type MediaRepo interface {
 	Store(file io.Reader, group string, ID string, size int64) (URI string, err error)
 	GenerateUniqueName(fileName string) string
+	// URI returns the URI media stored in the group with the name has, without storing anything.
+	URI(group string, name string) string
+	Delete(URI string) error
+	Exists(URI string) (bool, error)
+	Stat(URI string) (MediaInfo, error)
+	// List returns the URIs of all media stored in the group.
+	List(group string) ([]string, error)
+	// Move moves the media into the group, returning its new URI.
+	Move(URI string, group string) (string, error)
+}
+
+// MediaInfo describes stored media.
+type MediaInfo struct {
+	URI         string    `json:"uri"`
+	Size        int64     `json:"size"`
+	ContentType string    `json:"contentType"`
+	Modified    time.Time `json:"modified"`
 }
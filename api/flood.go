@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/json"
+	"github.com/julienschmidt/httprouter"
+	"github.com/spf13/viper"
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Returns the action the request takes and how long a client must wait between taking it.
+type cooldown func(r *http.Request) (action string, wait time.Duration)
+
+func threadCooldown(_ *http.Request) (string, time.Duration) {
+	return "thread", viper.GetDuration("ratelimit.thread")
+}
+
+// Replies with an image have their own, usually longer, cooldown.
+// The form is parsed with the same memory limit as the handler it is checked for, rather than FormFile's default.
+func replyCooldown(r *http.Request) (string, time.Duration) {
+	if err := r.ParseMultipartForm(10 << 20); err == nil && len(r.MultipartForm.File["image"]) > 0 {
+		return "image", viper.GetDuration("ratelimit.image")
+	}
+	return "reply", viper.GetDuration("ratelimit.reply")
+}
+
+// rateLimited refuses requests from a client that took the same action within its cooldown with 429 Too Many Requests.
+// Requests that fail do not count, so a client can correct a rejected post straight away.
+func rateLimited(cooldown cooldown, h httprouter.Handle) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		action, wait := cooldown(r)
+		client := clientIP(r)
+		allowed, retryAfter, err := limiter.Allow(action, client, wait)
+		if err != nil {
+			log.Printf("Could not check rate limit of %s for %s: %v", action, client, err)
+		}
+		if err == nil && !allowed {
+			seconds := int(math.Ceil(retryAfter.Seconds()))
+			w.Header().Set("Retry-After", strconv.Itoa(seconds))
+			w.WriteHeader(http.StatusTooManyRequests)
+			_ = json.NewEncoder(w).Encode(boardResponse{Status: "FAILURE", Code: "RATE_LIMITED", Error: "wait before posting again", RetryAfter: seconds})
+			return
+		}
+
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		h(recorder, r, ps)
+		if allowed && recorder.status >= http.StatusBadRequest {
+			_ = limiter.Reset(action, client)
+		}
+	}
+}
+
+// Returns the IP address the request came from.
+// Requests through trusted proxies come from the last address in X-Forwarded-For that is not a trusted proxy,
+// as addresses before it could have been sent by the client.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	proxies := viper.GetStringSlice("server.trustedProxies")
+	if !trustedProxy(host, proxies) {
+		return host
+	}
+	forwarded := strings.Split(r.Header.Get("X-Forwarded-For"), ",")
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		ip := strings.TrimSpace(forwarded[i])
+		if ip == "" {
+			break
+		}
+		host = ip
+		if !trustedProxy(ip, proxies) {
+			break
+		}
+	}
+	return host
+}
+
+// Returns whether the IP address is one of the proxies, given as addresses or CIDR ranges.
+func trustedProxy(ip string, proxies []string) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	for _, proxy := range proxies {
+		if _, network, err := net.ParseCIDR(proxy); err == nil && network.Contains(parsed) {
+			return true
+		}
+		if proxyIP := net.ParseIP(proxy); proxyIP != nil && proxyIP.Equal(parsed) {
+			return true
+		}
+	}
+	return false
+}
+
+// Records the status code written to the response.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
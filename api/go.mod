@@ -3,6 +3,8 @@ module github.com/alice-ws/alice
 go 1.12
 
 require (
+	github.com/alicebob/miniredis/v2 v2.11.0
+	github.com/aquilax/tripcode v1.0.0
 	github.com/dgrijalva/jwt-go v3.2.0+incompatible
 	github.com/go-redis/redis v6.15.2+incompatible
 	github.com/julienschmidt/httprouter v1.2.0
@@ -12,4 +14,5 @@ require (
 	github.com/rs/cors v1.7.0
 	github.com/spf13/viper v1.4.0
 	golang.org/x/crypto v0.0.0-20190513172903-22d7a77e9e5f
+	golang.org/x/image v0.0.0-20190802002840-cff245a6509b
 )
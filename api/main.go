@@ -1,11 +1,17 @@
 package main
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"github.com/alice-ws/alice/auth"
+	"github.com/alice-ws/alice/ban"
 	"github.com/alice-ws/alice/board"
 	"github.com/alice-ws/alice/data"
 	"github.com/alice-ws/alice/dependencies"
+	"github.com/alice-ws/alice/media"
+	"github.com/alice-ws/alice/ratelimit"
 	"github.com/julienschmidt/httprouter"
 	"github.com/rs/cors"
 	"github.com/spf13/viper"
@@ -17,9 +23,33 @@ import (
 
 var dependencyManagement dependencies.Dependencies
 
-var threadStore *board.Store
+// Stores for every board served, keyed by board ID
+var boards map[string]*board.Store
+
+// Board used by routes that do not name a board
+var defaultBoardID string
 var mediaRepo data.MediaRepo
 
+// Locks on media while it is reused or removed
+var mediaLocks *media.Locks
+
+// Files banned from every board by their exact hash
+var fileBans *media.BanList
+
+// Images banned from every board along with images that look like them
+var imageBans *media.BanList
+
+// Limits how often clients can post
+var limiter *ratelimit.Limiter
+
+// Bans on posters' IP addresses
+var posterBans *ban.Store
+
+// Secret salt for the hashes of posters' IP addresses
+var ipSalt []byte
+
+var authenticator *auth.Authenticator
+
 type statusResponse struct {
 	Status string `json:"status"`
 }
@@ -35,12 +65,31 @@ func configuration() {
 	_ = viper.BindEnv("minio.secret", "MINIO_SECRET_KEY")
 	viper.SetDefault("minio.access", "minio")
 	viper.SetDefault("minio.secret", "insecure")
-	viper.SetDefault("jwt.key", "KEYGOESHERE")
-	viper.SetDefault("users", map[string]string{"alice": "admin"})
+	// There is no default key, as anyone could sign tokens with it. Logging in is disabled until a key is set.
+	_ = viper.BindEnv("jwt.key", "JWT_KEY")
+	viper.SetDefault("jwt.expiry", "24h")
+	viper.SetDefault("archive.media.group", "")
+	viper.SetDefault("media.reconcile.interval", "0")
+	viper.SetDefault("media.reconcile.grace", "1h")
+	// Images within this many bits of a banned image's perceptual hash are refused
+	viper.SetDefault("media.perceptual.threshold", 10)
+	// Cooldowns between a client's posts. A cooldown of 0 turns off rate limiting.
+	viper.SetDefault("ratelimit.thread", "60s")
+	viper.SetDefault("ratelimit.reply", "10s")
+	viper.SetDefault("ratelimit.image", "30s")
+	// Proxies, as addresses or CIDR ranges, trusted to give the client's address in X-Forwarded-For
+	viper.SetDefault("server.trustedProxies", []string{})
+	// Secret that keys secure tripcodes. Changing it changes every poster's secure tripcode.
+	// There is no default, as a known secret lets secure tripcodes be cracked, so a random secret kept in the DB is used if it is not set.
+	_ = viper.BindEnv("trip.secret", "TRIP_SECRET")
+	// Secret salt for the hashes of posters' IP addresses. Changing it stops new posts matching old ones.
+	// There is no default, as a known salt lets the hashes be reversed, so a random salt kept in the DB is used if it is not set.
+	_ = viper.BindEnv("ip.salt", "IP_SALT")
 
 	dir, _ := os.Getwd()
 	viper.SetDefault("board.ID", "/obj/")
 	_ = viper.BindEnv("board.ID", "BOARD_ID")
+	viper.SetDefault("boards", map[string]interface{}{"/obj/": map[string]interface{}{"name": "obj"}})
 	viper.SetDefault("board.images.dir", filepath.Join(filepath.Dir(dir), "/web/public/images"))
 
 	viper.SetConfigName("config") // name of config file (without extension)
@@ -90,11 +139,46 @@ func handler() http.Handler {
 	router.GET("/", homePageHandler)
 	router.GET("/ready", readyHandler)
 	router.GET("/thread/all", getAllThreadsHandler)
-	router.POST("/thread", addThreadHandler)
+	router.GET("/catalog", getCatalogHandler)
+	router.POST("/thread", notBanned(rateLimited(threadCooldown, addThreadHandler)))
 	router.GET("/thread", getThreadHandler)
-	router.POST("/post", addPostHandler)
+	router.POST("/post", notBanned(rateLimited(replyCooldown, addPostHandler)))
+	router.DELETE("/thread", authorised(auth.Moderator, deleteThreadHandler))
+	router.DELETE("/post", authorised(auth.Janitor, deletePostHandler))
+	router.DELETE("/post/file", authorised(auth.Janitor, deleteFileHandler))
+	router.DELETE("/boards/:board/thread", authorised(auth.Moderator, deleteThreadHandler))
+	router.DELETE("/boards/:board/post", authorised(auth.Janitor, deletePostHandler))
+	router.DELETE("/boards/:board/post/file", authorised(auth.Janitor, deleteFileHandler))
+	router.GET("/admin/media/unreferenced", authorised(auth.Admin, getUnreferencedMediaHandler))
+	router.DELETE("/admin/media/unreferenced", authorised(auth.Admin, deleteUnreferencedMediaHandler))
+	router.GET("/admin/media/banned", authorised(auth.Moderator, getBansHandler(bannedFiles)))
+	router.POST("/admin/media/banned", authorised(auth.Moderator, addBanHandler(bannedFiles)))
+	router.DELETE("/admin/media/banned", authorised(auth.Moderator, deleteBanHandler(bannedFiles)))
+	router.GET("/admin/media/banned/images", authorised(auth.Moderator, getBansHandler(bannedImages)))
+	router.POST("/admin/media/banned/images", authorised(auth.Moderator, addBanHandler(bannedImages)))
+	router.DELETE("/admin/media/banned/images", authorised(auth.Moderator, deleteBanHandler(bannedImages)))
+	router.GET("/admin/bans", authorised(auth.Moderator, getPosterBansHandler))
+	router.POST("/admin/bans", authorised(auth.Moderator, addPosterBanHandler))
+	router.DELETE("/admin/bans", authorised(auth.Moderator, liftPosterBanHandler))
+	router.GET("/admin/posts", authorised(auth.Moderator, getPosterPostsHandler))
+	router.POST("/login", loginHandler)
+	router.GET("/user", authorised(auth.Janitor, userHandler))
+	router.GET("/archive", getArchivedThreadsHandler)
+	router.GET("/archive/thread", getArchivedThreadHandler)
+	router.GET("/boards", getBoardsHandler)
+	router.GET("/boards/:board", getBoardHandler)
+	router.GET("/boards/:board/thread/all", getAllThreadsHandler)
+	router.GET("/boards/:board/catalog", getCatalogHandler)
+	router.POST("/boards/:board/thread", notBanned(rateLimited(threadCooldown, addThreadHandler)))
+	router.GET("/boards/:board/thread", getThreadHandler)
+	router.POST("/boards/:board/post", notBanned(rateLimited(replyCooldown, addPostHandler)))
+	router.GET("/boards/:board/archive", getArchivedThreadsHandler)
+	router.GET("/boards/:board/archive/thread", getArchivedThreadHandler)
 
-	return cors.Default().Handler(router)
+	return cors.New(cors.Options{
+		AllowedMethods: []string{http.MethodGet, http.MethodPost, http.MethodDelete, http.MethodHead},
+		AllowedHeaders: []string{"Origin", "Accept", "Content-Type", "X-Requested-With", "Authorization"},
+	}).Handler(router)
 }
 
 func addHeaders(w http.ResponseWriter) {
@@ -120,10 +204,90 @@ func setup() string {
 	mc := dependencyManagement.GetImageRepository()
 	mediaRepo = mc
 
+	if viper.GetString("jwt.key") == "" {
+		log.Printf("No jwt.key is configured, so logging in is disabled")
+	}
+	authenticator = auth.NewAuthenticator([]byte(viper.GetString("jwt.key")), users(), viper.GetDuration("jwt.expiry"))
+
 	db := dependencyManagement.GetDB()
-	boardID := viper.GetString("board.ID")
-	threadStore = board.NewStore(boardID, db, db)
+	ipSalt = []byte(secret(db, "ip.salt"))
+	tripSecret := []byte(secret(db, "trip.secret"))
+
+	fileBans = media.NewBanList(db, db)
+	imageBans = media.NewImageBanList(db, db)
+	limiter = ratelimit.NewLimiter(db)
+	posterBans = ban.NewStore(db, db)
+	mediaLocks = media.NewLocks(db)
+	defaultBoardID = viper.GetString("board.ID")
+	boards = make(map[string]*board.Store)
+	for boardID, settings := range boardSettings() {
+		store := board.NewStore(boardID, settings, db, db)
+		store.SetTripSecret(tripSecret)
+		migrated, err := store.MigrateThreadKeys()
+		if err != nil {
+			log.Printf("Error migrating thread keys for board %s: %v", boardID, err)
+		} else if migrated > 0 {
+			log.Printf("Migrated %d threads of board %s to namespaced keys", migrated, boardID)
+		}
+		boards[boardID] = store
+		log.Printf("Serving board %s", boardID)
+	}
+
+	go scheduleMediaReconciliation(viper.GetDuration("media.reconcile.interval"))
 
 	log.Printf("Starting on " + port)
 	return port
 }
+
+// Returns the settings of every configured board, always including the default board.
+func boardSettings() map[string]board.Settings {
+	settings := make(map[string]board.Settings)
+	err := viper.UnmarshalKey("boards", &settings)
+	if err != nil {
+		log.Printf("Could not read boards configuration: %v", err)
+	}
+
+	if _, ok := settings[defaultBoardID]; !ok {
+		settings[defaultBoardID] = board.DefaultSettings(defaultBoardID)
+	}
+	return settings
+}
+
+// Returns key for a random secret that is stored in the DB
+func secretKey(key string) string {
+	return "secret:" + key
+}
+
+// Returns the configured secret, or a random secret if none is configured.
+// The random secret is kept in the DB so every replica of the API uses the same one, and it lasts across restarts.
+func secret(db data.KeyValueDB, key string) string {
+	if s := viper.GetString(key); s != "" {
+		return s
+	}
+
+	random := make([]byte, 32)
+	if _, err := rand.Read(random); err != nil {
+		log.Fatalf("Could not generate a random %s: %v", key, err)
+	}
+	// Only the first replica to start sets the secret, the rest use the one it set
+	if _, err := db.SetIfAbsent(data.NewKeyValuePair(secretKey(key), hex.EncodeToString(random)), 0); err != nil {
+		log.Fatalf("Could not store a random %s: %v", key, err)
+	}
+	s, err := db.Get(secretKey(key))
+	if err != nil {
+		log.Fatalf("Could not read the random %s: %v", key, err)
+	}
+	log.Printf("No %s is configured, so a random one kept in the DB is used", key)
+	return s
+}
+
+// Returns the configured users that can log in, keyed by username with bcrypt password hashes.
+// There are no users by default.
+func users() map[string]auth.User {
+	users := make(map[string]auth.User)
+	err := viper.UnmarshalKey("users", &users)
+	if err != nil {
+		log.Printf("Could not read users configuration: %v", err)
+	}
+	return users
+}
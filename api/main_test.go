@@ -1,15 +1,35 @@
 package main
 
 import (
+	"bytes"
 	"encoding/json"
+	"github.com/alice-ws/alice/auth"
+	"github.com/alice-ws/alice/ban"
+	"github.com/alice-ws/alice/board"
+	"github.com/alice-ws/alice/data"
+	"github.com/alice-ws/alice/media"
+	"github.com/alice-ws/alice/ratelimit"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/spf13/viper"
+	imagepkg "image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
 	"io"
+	"io/ioutil"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"path/filepath"
 	"reflect"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 )
 
-var tokenKey = []byte("KEYGOESHERE")
+var tokenKey = []byte("test key")
 
 func Test_homepageHandler(t *testing.T) {
 	endpoint := "/"
@@ -25,9 +45,708 @@ func Test_homepageHandler(t *testing.T) {
 	checkBody(rr.Body.String(), expected, t)
 }
 
+func Test_getBoardsHandler(t *testing.T) {
+	useMemoryBoards("/obj/", "/a/")
+
+	rr := createRequestAndServe("GET", "/boards", nil, requestCreatorForm)
+
+	checkStatusCode(rr.Code, http.StatusOK, t)
+	var got map[string]board.Settings
+	_ = json.Unmarshal(rr.Body.Bytes(), &got)
+	if got["/obj/"].Name != "obj" || got["/a/"].Name != "a" {
+		t.Errorf("handler returned unexpected boards: %v", got)
+	}
+}
+
+func Test_unknownBoardIsNotFound(t *testing.T) {
+	useMemoryBoards("/obj/")
+
+	rr := createRequestAndServe("GET", "/boards/a/thread/all", nil, requestCreatorForm)
+
+	checkStatusCode(rr.Code, http.StatusNotFound, t)
+}
+
+func Test_boardSettings(t *testing.T) {
+	defaultBoardID = "/obj/"
+	viper.Set("boards", map[string]interface{}{
+		"/a/": map[string]interface{}{"name": "Animals", "maxFileSize": 1024, "fileTypes": []string{".png"}},
+	})
+	defer viper.Set("boards", nil)
+
+	settings := boardSettings()
+
+	expected := board.Settings{Name: "Animals", MaxFileSize: 1024, FileTypes: []string{".png"}}
+	if !reflect.DeepEqual(settings["/a/"], expected) {
+		t.Errorf("got settings %v want %v", settings["/a/"], expected)
+	}
+	if _, ok := settings["/obj/"]; !ok {
+		t.Errorf("expected default board to be configured")
+	}
+}
+
+func Test_loginHandler(t *testing.T) {
+	useAuthenticator()
+	form := url.Values{"username": {"alice"}, "password": {"insecure"}}
+
+	rr := createRequestAndServe("POST", "/login", strings.NewReader(form.Encode()), requestCreatorForm)
+
+	checkStatusCode(rr.Code, http.StatusOK, t)
+	var got userResponse
+	_ = json.Unmarshal(rr.Body.Bytes(), &got)
+	if claims, err := authenticator.Validate(got.Token); err != nil || claims.Role != auth.Admin {
+		t.Errorf("handler returned invalid token %s: %v", got.Token, err)
+	}
+}
+
+func Test_loginHandler_wrongPassword(t *testing.T) {
+	useAuthenticator()
+	form := url.Values{"username": {"alice"}, "password": {"wrong"}}
+
+	rr := createRequestAndServe("POST", "/login", strings.NewReader(form.Encode()), requestCreatorForm)
+
+	checkStatusCode(rr.Code, http.StatusUnauthorized, t)
+}
+
+func Test_userHandler(t *testing.T) {
+	useAuthenticator()
+
+	rr := createRequestAndServe("GET", "/user", nil, requestCreatorWithToken(token("bob", auth.Janitor)))
+
+	checkStatusCode(rr.Code, http.StatusOK, t)
+	checkBody(rr.Body.String(), `{"status":"SUCCESS","username":"bob","error":"","token":"","role":"janitor"}`, t)
+}
+
+func Test_userHandler_requiresToken(t *testing.T) {
+	useAuthenticator()
+
+	for name, creator := range map[string]func(string, string, io.Reader) *http.Request{
+		"no token":      requestCreatorForm,
+		"invalid token": requestCreatorWithToken("invalid"),
+		"unknown role":  requestCreatorWithToken(token("bob", auth.Role("poster"))),
+	} {
+		t.Run(name, func(t *testing.T) {
+			rr := createRequestAndServe("GET", "/user", nil, creator)
+			checkStatusCode(rr.Code, http.StatusUnauthorized, t)
+		})
+	}
+}
+
+func Test_deleteThreadHandler(t *testing.T) {
+	useAuthenticator()
+	useMemoryBoards("/obj/")
+	useLocalMedia(t)
+	URI, _ := mediaRepo.Store(strings.NewReader("image"), "images", "0.png", 5)
+	op := board.CreatePost("", "", "OP")
+	op.Image = URI
+	no, _ := boards["/obj/"].AddThread(board.NewThread(op, ""))
+
+	rr := createRequestAndServe("DELETE", "/boards/obj/thread?no="+strconv.FormatUint(no, 10), nil, requestCreatorWithToken(token("bob", auth.Moderator)))
+
+	checkStatusCode(rr.Code, http.StatusOK, t)
+	if _, err := boards["/obj/"].GetThread(strconv.FormatUint(no, 10)); err == nil {
+		t.Errorf("expected thread to be deleted")
+	}
+	if err := mediaRepo.Delete(URI); err == nil {
+		t.Errorf("expected media %s to be deleted", URI)
+	}
+}
+
+func Test_deleteThreadHandler_requiresModerator(t *testing.T) {
+	useAuthenticator()
+	useMemoryBoards("/obj/")
+	no, _ := boards["/obj/"].AddThread(board.NewThread(board.CreatePost("", "", "OP"), ""))
+
+	rr := createRequestAndServe("DELETE", "/thread?no="+strconv.FormatUint(no, 10), nil, requestCreatorWithToken(token("bob", auth.Janitor)))
+
+	checkStatusCode(rr.Code, http.StatusForbidden, t)
+	if _, err := boards["/obj/"].GetThread(strconv.FormatUint(no, 10)); err != nil {
+		t.Errorf("expected thread to be kept: %v", err)
+	}
+}
+
+func Test_deletePostHandler(t *testing.T) {
+	useAuthenticator()
+	useMemoryBoards("/obj/")
+	threadNo, _ := boards["/obj/"].AddThread(board.NewThread(board.CreatePost("", "", "OP"), ""))
+	no, _ := boards["/obj/"].AddPost(strconv.FormatUint(threadNo, 10), board.CreatePost("", "", "reply"))
+
+	endpoint := "/post?threadNo=" + strconv.FormatUint(threadNo, 10) + "&no=" + strconv.FormatUint(no, 10)
+	rr := createRequestAndServe("DELETE", endpoint, nil, requestCreatorWithToken(token("bob", auth.Janitor)))
+
+	checkStatusCode(rr.Code, http.StatusOK, t)
+	rr = createRequestAndServe("DELETE", endpoint, nil, requestCreatorWithToken(token("bob", auth.Janitor)))
+	checkStatusCode(rr.Code, http.StatusNotFound, t)
+}
+
+func Test_unreferencedMedia(t *testing.T) {
+	useMemoryBoards("/obj/", "/a/")
+	useLocalMedia(t)
+	used, _ := mediaRepo.Store(strings.NewReader("image"), "images", "0.png", 5)
+	unused, _ := mediaRepo.Store(strings.NewReader("image"), "images", "1.png", 5)
+	threadNo, _ := boards["/a/"].AddThread(board.NewThread(board.CreatePost("", "", "OP"), ""))
+	reply := board.CreatePost("", "", "reply")
+	reply.Image = used
+	_, _ = boards["/a/"].AddPost(strconv.FormatUint(threadNo, 10), reply)
+
+	got, err := unreferencedMedia(0)
+
+	if err != nil || !reflect.DeepEqual(got, []string{unused}) {
+		t.Errorf("unreferencedMedia() = %v, %v, want %v", got, err, []string{unused})
+	}
+	if got, _ := unreferencedMedia(time.Hour); len(got) != 0 {
+		t.Errorf("expected new media to be skipped, got %v", got)
+	}
+}
+
+func Test_getAllThreadsHandler_paginates(t *testing.T) {
+	useMemoryBoards("/obj/")
+	for i := 0; i < 3; i++ {
+		_, _ = boards["/obj/"].AddThread(board.NewThread(board.CreatePost("", "", "OP"), ""))
+	}
+
+	rr := createRequestAndServe("GET", "/thread/all?page=2&perPage=2", nil, requestCreatorForm)
+
+	checkStatusCode(rr.Code, http.StatusOK, t)
+	var got board.Page
+	_ = json.Unmarshal(rr.Body.Bytes(), &got)
+	if got.Page != 2 || got.PerPage != 2 || got.TotalPages != 2 || len(got.Threads) != 1 || got.Threads[0].No != 0 {
+		t.Errorf("handler returned unexpected page: %v", got)
+	}
+}
+
+func Test_getAllThreadsHandler_invalidPage(t *testing.T) {
+	useMemoryBoards("/obj/")
+
+	for _, query := range []string{"page=0", "page=a", "perPage=0"} {
+		rr := createRequestAndServe("GET", "/thread/all?"+query, nil, requestCreatorForm)
+		checkStatusCode(rr.Code, http.StatusBadRequest, t)
+	}
+}
+
+func Test_getCatalogHandler(t *testing.T) {
+	useMemoryBoards("/obj/", "/b/")
+	_, _ = boards["/b/"].AddThread(board.NewThread(board.NewPost(0, time.Now(), "", "", "Hello", "", "", ""), "Catalog"))
+
+	rr := createRequestAndServe("GET", "/boards/b/catalog?sort=replies", nil, requestCreatorForm)
+	checkStatusCode(rr.Code, http.StatusOK, t)
+	var catalog []board.CatalogEntry
+	if err := json.NewDecoder(rr.Body).Decode(&catalog); err != nil || len(catalog) != 1 || catalog[0].Subject != "Catalog" {
+		t.Errorf("catalog = %v, %v, want the thread", catalog, err)
+	}
+
+	rr = createRequestAndServe("GET", "/catalog?sort=random", nil, requestCreatorForm)
+	checkStatusCode(rr.Code, http.StatusBadRequest, t)
+}
+
+func Test_addThreadHandler_thumbnail(t *testing.T) {
+	useMemoryBoards("/obj/")
+	useLocalMedia(t)
+	var image bytes.Buffer
+	_ = png.Encode(&image, imagepkg.NewRGBA(imagepkg.Rect(0, 0, 500, 300)))
+
+	rr := createRequestAndServe("POST", "/thread", multipartForm(t, map[string]string{"subject": "Thumbnail"}, "image.png", image.Bytes()), requestCreatorMultipart)
+
+	checkStatusCode(rr.Code, http.StatusCreated, t)
+	thread, _ := boards["/obj/"].GetThread("0")
+	if thread.Thumbnail == "" || thread.ThumbnailWidth != 250 || thread.ThumbnailHeight != 150 || thread.Width != 500 || thread.Height != 300 || thread.Size != int64(image.Len()) {
+		t.Errorf("thread = %+v, want a thumbnail of the 500x300 image", thread.Post)
+	}
+	if exists, _ := mediaRepo.Exists(thread.Thumbnail); !exists {
+		t.Errorf("thumbnail %s was not stored", thread.Thumbnail)
+	}
+}
+
+func Test_addPostHandler_rejectsUploads(t *testing.T) {
+	useMemoryBoards("/obj/")
+	useLocalMedia(t)
+	threadNo, _ := boards["/obj/"].AddThread(board.NewThread(board.CreatePost("", "", "OP"), ""))
+	fields := map[string]string{"threadNo": strconv.FormatUint(threadNo, 10), "comment": "reply"}
+
+	tests := []struct {
+		name     string
+		filename string
+		file     []byte
+		wantCode string
+	}{
+		{name: "renamed executable", filename: "x.png", file: []byte("MZ\x90\x00"), wantCode: "TYPE_MISMATCH"},
+		{name: "disallowed type", filename: "x.exe", file: []byte("MZ\x90\x00"), wantCode: "UNSUPPORTED_TYPE"},
+		{name: "too large", filename: "x.png", file: make([]byte, 10<<20+1), wantCode: "FILE_TOO_LARGE"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := createRequestAndServe("POST", "/post", multipartForm(t, fields, tt.filename, tt.file), requestCreatorMultipart)
+
+			checkStatusCode(rr.Code, http.StatusBadRequest, t)
+			var got boardResponse
+			_ = json.Unmarshal(rr.Body.Bytes(), &got)
+			if got.Code != tt.wantCode {
+				t.Errorf("handler rejected upload with code %q, want %q", got.Code, tt.wantCode)
+			}
+		})
+	}
+	if thread, _ := boards["/obj/"].GetThread(strconv.FormatUint(threadNo, 10)); len(thread.Replies) != 0 {
+		t.Errorf("expected rejected replies not to be added, got %v", thread.Replies)
+	}
+}
+
+func Test_addThreadHandler_stripsMetadata(t *testing.T) {
+	useMemoryBoards("/obj/")
+	dir := useLocalMedia(t)
+	db := data.NewMemoryDB()
+	boards["/obj/"] = board.NewStore("/obj/", board.Settings{StripMetadata: true}, db, db)
+	var image bytes.Buffer
+	_ = jpeg.Encode(&image, imagepkg.NewRGBA(imagepkg.Rect(0, 0, 10, 10)), nil)
+	exif := append([]byte{0xff, 0xe1, 0x00, 0x12}, "Exif\x00\x00GPS 51.5 N"...)
+	withExif := append(append(append([]byte{}, image.Bytes()[:2]...), exif...), image.Bytes()[2:]...)
+
+	rr := createRequestAndServe("POST", "/thread", multipartForm(t, map[string]string{}, "photo.jpg", withExif), requestCreatorMultipart)
+
+	checkStatusCode(rr.Code, http.StatusCreated, t)
+	thread, _ := boards["/obj/"].GetThread("0")
+	stored, err := ioutil.ReadFile(filepath.Join(dir, thread.Image))
+	if err != nil || bytes.Contains(stored, []byte("GPS")) || thread.Size != int64(len(stored)) {
+		t.Errorf("stored image %s still has its metadata or the wrong size: %v", thread.Image, err)
+	}
+}
+
+func Test_addPostHandler_deduplicatesFiles(t *testing.T) {
+	useMemoryBoards("/obj/")
+	dir := useLocalMedia(t)
+	threadNo, _ := boards["/obj/"].AddThread(board.NewThread(board.CreatePost("", "", "OP"), ""))
+	fields := map[string]string{"threadNo": strconv.FormatUint(threadNo, 10)}
+	var image bytes.Buffer
+	_ = png.Encode(&image, imagepkg.NewRGBA(imagepkg.Rect(0, 0, 10, 10)))
+
+	for i := 0; i < 2; i++ {
+		rr := createRequestAndServe("POST", "/post", multipartForm(t, fields, "image"+strconv.Itoa(i)+".png", image.Bytes()), requestCreatorMultipart)
+		checkStatusCode(rr.Code, http.StatusCreated, t)
+	}
+
+	thread, _ := boards["/obj/"].GetThread(strconv.FormatUint(threadNo, 10))
+	first, second := thread.Replies[0], thread.Replies[1]
+	if first.Hash != media.Hash(image.Bytes()) || first.Image != second.Image || first.Thumbnail != second.Thumbnail {
+		t.Errorf("expected both replies to share the file %s", first.Hash)
+	}
+	if files, _ := ioutil.ReadDir(dir); len(files) != 2 {
+		t.Errorf("expected the image and thumbnail to be stored once, got %d files", len(files))
+	}
+
+	removeMedia(first)
+	if exists, _ := mediaRepo.Exists(second.Image); !exists {
+		t.Errorf("expected media still used by a reply to be kept")
+	}
+}
+
+func Test_removeMedia_waitsForReuse(t *testing.T) {
+	useMemoryBoards("/obj/")
+	useLocalMedia(t)
+	store := boards["/obj/"]
+	URI, release, _ := storeOnce([]byte("file"), "file.png")
+	release()
+	op := board.CreatePost("", "", "OP")
+	op.Image = URI
+	threadNo, _ := store.AddThread(board.NewThread(op, ""))
+	deleted, _ := store.DeleteThread(strconv.FormatUint(threadNo, 10))
+
+	// The file is uploaded again while the deleted thread's media is being removed
+	_, release, _ = storeOnce([]byte("file"), "file.png")
+	removed := make(chan bool)
+	go func() {
+		removeMedia(deleted.Posts()...)
+		close(removed)
+	}()
+	reupload := board.CreatePost("", "", "OP")
+	reupload.Image = URI
+	_, _ = store.AddThread(board.NewThread(reupload, ""))
+	release()
+	<-removed
+
+	if exists, _ := mediaRepo.Exists(URI); !exists {
+		t.Errorf("expected media reused by a new thread to be kept")
+	}
+}
+
+func Test_removeMedia_ignoresArchivedCopies(t *testing.T) {
+	useMemoryBoards("/obj/")
+	db := data.NewMemoryDB()
+	store := board.NewStore("/obj/", board.Settings{Archive: true, MaxThreads: 1}, db, db)
+	boards["/obj/"] = store
+	mediaRepo = &movingRepo{MediaRepo: data.NewLocalRepo(useLocalMedia(t))}
+	viper.Set("archive.media.group", "archive")
+	defer viper.Set("archive.media.group", nil)
+	addThread := func(file string) board.Post {
+		URI, release, _ := storeOnce([]byte(file), file+".png")
+		op := board.CreatePost("", "", "OP")
+		op.Image, op.Hash = URI, file
+		no, _ := store.AddThread(board.NewThread(op, ""))
+		release()
+		prune(store)
+		thread, _ := store.GetThread(strconv.FormatUint(no, 10))
+		return thread.Post
+	}
+
+	// The file is moved to the archive with its thread, then uploaded again
+	addThread("file")
+	addThread("other")
+	reupload := addThread("file")
+	if archived, _ := store.GetArchivedThread("0"); archived.Image != "archive/file.png" {
+		t.Fatalf("archived thread points at %s, want its file moved to the archive", archived.Image)
+	}
+	_, _ = store.DeleteThread(reupload.Key())
+	removeMedia(reupload)
+
+	if exists, _ := mediaRepo.Exists(reupload.Image); exists {
+		t.Errorf("expected media to be removed when only its copy in the archive is used")
+	}
+}
+
+func Test_prune_keepsSharedFilesOfArchivedThreads(t *testing.T) {
+	useMemoryBoards("/obj/")
+	db := data.NewMemoryDB()
+	store := board.NewStore("/obj/", board.Settings{Archive: true, MaxThreads: 1}, db, db)
+	boards["/obj/"] = store
+	repo := &movingRepo{MediaRepo: data.NewLocalRepo(useLocalMedia(t))}
+	mediaRepo = repo
+	viper.Set("archive.media.group", "archive")
+	defer viper.Set("archive.media.group", nil)
+	addThread := func(hash string) {
+		op := board.CreatePost("", "", "OP")
+		op.Image, op.Hash = hash+".png", hash
+		_, _ = store.AddThread(board.NewThread(op, ""))
+		prune(store)
+	}
+
+	addThread("shared")
+	addThread("shared")
+	addThread("unique")
+	addThread("other")
+
+	if len(repo.moved) != 1 || repo.moved[0] != "unique.png" {
+		t.Errorf("moved %v, want only the file no other thread has", repo.moved)
+	}
+	threads, _ := store.GetArchivedThreads()
+	for _, thread := range threads {
+		if thread.Hash == "shared" && thread.Image != "shared.png" {
+			t.Errorf("archived thread %d points at %s, want the shared file where it was", thread.No, thread.Image)
+		}
+	}
+}
+
+func Test_addPostHandler_rejectsBannedFilesAndReposts(t *testing.T) {
+	useMemoryBoards("/obj/")
+	useLocalMedia(t)
+	db := data.NewMemoryDB()
+	boards["/obj/"] = board.NewStore("/obj/", board.Settings{RejectReposts: true}, db, db)
+	threadNo, _ := boards["/obj/"].AddThread(board.NewThread(board.CreatePost("", "", "OP"), ""))
+	fields := map[string]string{"threadNo": strconv.FormatUint(threadNo, 10)}
+	var banned, posted bytes.Buffer
+	_ = png.Encode(&banned, imagepkg.NewRGBA(imagepkg.Rect(0, 0, 10, 10)))
+	_ = png.Encode(&posted, imagepkg.NewRGBA(imagepkg.Rect(0, 0, 20, 20)))
+	_, _ = fileBans.Ban(media.Hash(banned.Bytes()), "spam")
+
+	rr := createRequestAndServe("POST", "/post", multipartForm(t, fields, "banned.png", banned.Bytes()), requestCreatorMultipart)
+	checkStatusCode(rr.Code, http.StatusBadRequest, t)
+	var got boardResponse
+	_ = json.Unmarshal(rr.Body.Bytes(), &got)
+	if got.Code != "BANNED_FILE" {
+		t.Errorf("handler rejected banned file with code %q, want BANNED_FILE", got.Code)
+	}
+
+	rr = createRequestAndServe("POST", "/post", multipartForm(t, fields, "posted.png", posted.Bytes()), requestCreatorMultipart)
+	checkStatusCode(rr.Code, http.StatusCreated, t)
+	rr = createRequestAndServe("POST", "/post", multipartForm(t, fields, "repost.png", posted.Bytes()), requestCreatorMultipart)
+	checkStatusCode(rr.Code, http.StatusBadRequest, t)
+	_ = json.Unmarshal(rr.Body.Bytes(), &got)
+	if got.Code != "REPOST" {
+		t.Errorf("handler rejected repost with code %q, want REPOST", got.Code)
+	}
+}
+
+func Test_addThreadHandler_rejectsSimilarImages(t *testing.T) {
+	useAuthenticator()
+	useMemoryBoards("/obj/")
+	useLocalMedia(t)
+	viper.Set("media.perceptual.threshold", 10)
+	defer viper.Set("media.perceptual.threshold", nil)
+	banned := imagepkg.NewGray(imagepkg.Rect(0, 0, 200, 200))
+	for x := 0; x < 200; x++ {
+		for y := 0; y < 200; y++ {
+			banned.SetGray(x, y, color.Gray{Y: uint8(x * y % 256)})
+		}
+	}
+	form := url.Values{"hash": {media.PerceptualHash(banned)}, "reason": {"spam"}}
+	rr := createRequestAndServe("POST", "/admin/media/banned/images", strings.NewReader(form.Encode()), requestCreatorWithToken(token("bob", auth.Moderator)))
+	checkStatusCode(rr.Code, http.StatusCreated, t)
+
+	var reencoded bytes.Buffer
+	_ = jpeg.Encode(&reencoded, banned, &jpeg.Options{Quality: 50})
+	rr = createRequestAndServe("POST", "/thread", multipartForm(t, map[string]string{}, "image.jpg", reencoded.Bytes()), requestCreatorMultipart)
+
+	checkStatusCode(rr.Code, http.StatusBadRequest, t)
+	var got boardResponse
+	_ = json.Unmarshal(rr.Body.Bytes(), &got)
+	if got.Code != "BANNED_IMAGE" {
+		t.Errorf("handler rejected image with code %q, want BANNED_IMAGE", got.Code)
+	}
+}
+
+func Test_fileBanHandlers(t *testing.T) {
+	useAuthenticator()
+	useMemoryBoards("/obj/")
+	moderator := requestCreatorWithToken(token("bob", auth.Moderator))
+	form := url.Values{"hash": {"abc"}, "reason": {"spam"}}
+
+	rr := createRequestAndServe("POST", "/admin/media/banned", strings.NewReader(form.Encode()), moderator)
+	checkStatusCode(rr.Code, http.StatusCreated, t)
+	rr = createRequestAndServe("GET", "/admin/media/banned", nil, moderator)
+	checkStatusCode(rr.Code, http.StatusOK, t)
+	var got fileBanResponse
+	_ = json.Unmarshal(rr.Body.Bytes(), &got)
+	if len(got.Bans) != 1 || got.Bans[0].Hash != "abc" || got.Bans[0].Reason != "spam" {
+		t.Errorf("handler returned unexpected bans: %v", got.Bans)
+	}
+
+	rr = createRequestAndServe("DELETE", "/admin/media/banned?hash=abc", nil, moderator)
+	checkStatusCode(rr.Code, http.StatusOK, t)
+	rr = createRequestAndServe("DELETE", "/admin/media/banned?hash=abc", nil, moderator)
+	checkStatusCode(rr.Code, http.StatusNotFound, t)
+	rr = createRequestAndServe("GET", "/admin/media/banned", nil, requestCreatorWithToken(token("bob", auth.Janitor)))
+	checkStatusCode(rr.Code, http.StatusForbidden, t)
+}
+
+func Test_rateLimited(t *testing.T) {
+	useMemoryBoards("/obj/")
+	useLocalMedia(t)
+	viper.Set("ratelimit.thread", "1m")
+	defer viper.Set("ratelimit.thread", nil)
+	var image bytes.Buffer
+	_ = png.Encode(&image, imagepkg.NewRGBA(imagepkg.Rect(0, 0, 10, 10)))
+	newThread := func(ip string, file []byte) *httptest.ResponseRecorder {
+		return createRequestAndServe("POST", "/thread", multipartForm(t, map[string]string{}, "image.png", file), requestCreatorFrom(ip))
+	}
+
+	checkStatusCode(newThread("1.2.3.4", []byte("not an image")).Code, http.StatusBadRequest, t)
+	checkStatusCode(newThread("1.2.3.4", image.Bytes()).Code, http.StatusCreated, t)
+	rr := newThread("1.2.3.4", image.Bytes())
+
+	checkStatusCode(rr.Code, http.StatusTooManyRequests, t)
+	var got boardResponse
+	_ = json.Unmarshal(rr.Body.Bytes(), &got)
+	if got.Code != "RATE_LIMITED" || got.RetryAfter != 60 || rr.Header().Get("Retry-After") != "60" {
+		t.Errorf("handler returned %v with Retry-After %s, want to retry after 60 seconds", got, rr.Header().Get("Retry-After"))
+	}
+	checkStatusCode(newThread("5.6.7.8", image.Bytes()).Code, http.StatusCreated, t)
+}
+
+func Test_replyCooldown(t *testing.T) {
+	withImage := requestCreatorMultipart("POST", "/post", multipartForm(t, map[string]string{"comment": "reply"}, "image.png", []byte("image")))
+	textOnly := requestCreatorMultipart("POST", "/post", multipartForm(t, map[string]string{"comment": "reply"}, "", nil))
+
+	if action, _ := replyCooldown(withImage); action != "image" {
+		t.Errorf("replyCooldown() = %s for a reply with an image, want image", action)
+	}
+	if action, _ := replyCooldown(textOnly); action != "reply" {
+		t.Errorf("replyCooldown() = %s for a reply without an image, want reply", action)
+	}
+	if withImage.FormValue("comment") != "reply" {
+		t.Errorf("expected the form to still be readable after checking the cooldown")
+	}
+}
+
+func Test_notBanned(t *testing.T) {
+	useMemoryBoards("/obj/", "/b/")
+	dir := useLocalMedia(t)
+	expires := time.Now().Add(time.Hour).Round(time.Second)
+	_, _ = posterBans.Add(ban.Ban{Range: "1.2.3.0/24", Board: "/obj/", Reason: "spam", Expires: expires})
+	var image bytes.Buffer
+	_ = png.Encode(&image, imagepkg.NewRGBA(imagepkg.Rect(0, 0, 10, 10)))
+
+	rr := createRequestAndServe("POST", "/thread", multipartForm(t, map[string]string{}, "image.png", image.Bytes()), requestCreatorFrom("1.2.3.4"))
+
+	checkStatusCode(rr.Code, http.StatusForbidden, t)
+	var got boardResponse
+	_ = json.Unmarshal(rr.Body.Bytes(), &got)
+	if got.Code != "BANNED" || got.Error != "spam" || got.Ban == nil || !got.Ban.Expires.Equal(expires) {
+		t.Errorf("handler returned %v, want the ban's reason and expiry", got)
+	}
+	if files, _ := ioutil.ReadDir(dir); len(files) != 0 {
+		t.Errorf("handler stored %d files for a banned poster, want none", len(files))
+	}
+	rr = createRequestAndServe("POST", "/boards/b/thread", multipartForm(t, map[string]string{}, "image.png", image.Bytes()), requestCreatorFrom("1.2.3.4"))
+	checkStatusCode(rr.Code, http.StatusCreated, t)
+	rr = createRequestAndServe("POST", "/thread", multipartForm(t, map[string]string{}, "image.png", image.Bytes()), requestCreatorFrom("1.2.4.4"))
+	checkStatusCode(rr.Code, http.StatusCreated, t)
+}
+
+func Test_notBanned_refusesWhenBansCannotBeChecked(t *testing.T) {
+	useMemoryBoards("/obj/")
+	db := data.NewMemoryDB()
+	_ = db.Set(data.NewKeyValuePair("ban:1", "not json"))
+	_ = db.SetOrdered(data.NewKeyValuePair("bans", "1"), 1)
+	posterBans = ban.NewStore(db, db)
+
+	rr := createRequestAndServe("POST", "/thread", multipartForm(t, map[string]string{"comment": "Hello"}, "", nil), requestCreatorFrom("1.2.3.4"))
+
+	checkStatusCode(rr.Code, http.StatusFailedDependency, t)
+	if _, err := boards["/obj/"].GetThread("0"); err == nil {
+		t.Errorf("expected no thread to be added")
+	}
+}
+
+func Test_posterBanHandlers(t *testing.T) {
+	useAuthenticator()
+	useMemoryBoards("/obj/")
+	moderator := requestCreatorWithToken(token("bob", auth.Moderator))
+	form := url.Values{"range": {"1.2.3.4"}, "board": {"obj"}, "reason": {"spam"}, "duration": {"72h"}}
+
+	rr := createRequestAndServe("POST", "/admin/bans", strings.NewReader(form.Encode()), moderator)
+	checkStatusCode(rr.Code, http.StatusCreated, t)
+	rr = createRequestAndServe("GET", "/admin/bans", nil, moderator)
+	checkStatusCode(rr.Code, http.StatusOK, t)
+	var got banResponse
+	_ = json.Unmarshal(rr.Body.Bytes(), &got)
+	if len(got.Bans) != 1 || got.Bans[0].Range != "1.2.3.4/32" || got.Bans[0].Board != "/obj/" || got.Bans[0].Expires.IsZero() {
+		t.Fatalf("handler returned unexpected bans: %v", got.Bans)
+	}
+
+	rr = createRequestAndServe("DELETE", "/admin/bans?id="+got.Bans[0].ID, nil, moderator)
+	checkStatusCode(rr.Code, http.StatusOK, t)
+	rr = createRequestAndServe("DELETE", "/admin/bans?id="+got.Bans[0].ID, nil, moderator)
+	checkStatusCode(rr.Code, http.StatusNotFound, t)
+
+	for _, invalid := range []url.Values{{"range": {"nonsense"}}, {"range": {"1.2.3.4"}, "board": {"x"}}, {"range": {"1.2.3.4"}, "duration": {"forever"}}} {
+		rr = createRequestAndServe("POST", "/admin/bans", strings.NewReader(invalid.Encode()), moderator)
+		checkStatusCode(rr.Code, http.StatusBadRequest, t)
+	}
+	rr = createRequestAndServe("GET", "/admin/bans", nil, requestCreatorWithToken(token("bob", auth.Janitor)))
+	checkStatusCode(rr.Code, http.StatusForbidden, t)
+}
+
+func Test_secret(t *testing.T) {
+	db := data.NewMemoryDB()
+	viper.Set("test.secret", "configured")
+	if got := secret(db, "test.secret"); got != "configured" {
+		t.Errorf("secret() = %s, want the configured secret", got)
+	}
+
+	viper.Set("test.secret", "")
+	defer viper.Set("test.secret", nil)
+	first, second := secret(db, "test.secret"), secret(db, "test.secret")
+	if len(first) != 64 || first != second {
+		t.Errorf("secret() = %s and %s, want the same random secret from the shared DB", first, second)
+	}
+	if other := secret(data.NewMemoryDB(), "test.secret"); other == first {
+		t.Errorf("secret() = %s for another DB, want a different random secret", other)
+	}
+}
+
+func Test_posterIDs_matchAcrossReplicas(t *testing.T) {
+	viper.Set("ip.salt", "configured")
+	defer viper.Set("ip.salt", nil)
+	defer func(salt []byte) { ipSalt = salt }(ipSalt)
+	// Each replica reads the salt and serves the board from its own store
+	posterID := func() string {
+		ipSalt = []byte(secret(data.NewMemoryDB(), "ip.salt"))
+		db := data.NewMemoryDB()
+		store := board.NewStore("/obj/", board.Settings{PosterIDs: true}, db, db)
+		op := board.CreatePost("", "", "OP")
+		op.IPHash = ipHash("1.2.3.4")
+		_, _ = store.AddThread(board.NewThread(op, ""))
+		thread, _ := store.GetThread("0")
+		return thread.PosterID
+	}
+
+	if first, second := posterID(), posterID(); first == "" || first != second {
+		t.Errorf("poster IDs = %q and %q, want the same ID from replicas with the same salt", first, second)
+	}
+}
+
+func Test_clientIP(t *testing.T) {
+	viper.Set("server.trustedProxies", []string{"10.0.0.0/8", "192.168.0.1"})
+	defer viper.Set("server.trustedProxies", nil)
+	tests := []struct {
+		name       string
+		remoteAddr string
+		forwarded  string
+		want       string
+	}{
+		{"direct", "1.2.3.4:1234", "", "1.2.3.4"},
+		{"ignores forwarded from untrusted", "1.2.3.4:1234", "5.6.7.8", "1.2.3.4"},
+		{"trusted proxy", "192.168.0.1:1234", "5.6.7.8", "5.6.7.8"},
+		{"skips trusted proxies", "10.0.0.1:1234", "5.6.7.8, 10.0.0.2", "5.6.7.8"},
+		{"ignores addresses before the client", "10.0.0.1:1234", "9.9.9.9, 5.6.7.8", "5.6.7.8"},
+		{"trusted proxy without forwarded", "10.0.0.1:1234", "", "10.0.0.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.forwarded != "" {
+				req.Header.Set("X-Forwarded-For", tt.forwarded)
+			}
+			if got := clientIP(req); got != tt.want {
+				t.Errorf("clientIP() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_ipHashes(t *testing.T) {
+	useAuthenticator()
+	useMemoryBoards("/obj/", "/b/")
+	useLocalMedia(t)
+	var image bytes.Buffer
+	_ = png.Encode(&image, imagepkg.NewRGBA(imagepkg.Rect(0, 0, 10, 10)))
+	_ = createRequestAndServe("POST", "/thread", multipartForm(t, map[string]string{}, "image.png", image.Bytes()), requestCreatorFrom("1.2.3.4"))
+	_ = createRequestAndServe("POST", "/boards/b/thread", multipartForm(t, map[string]string{}, "image.png", image.Bytes()), requestCreatorFrom("1.2.3.4"))
+	_ = createRequestAndServe("POST", "/boards/b/post", multipartForm(t, map[string]string{"threadNo": "0", "comment": "reply"}, "", nil), requestCreatorFrom("5.6.7.8"))
+	hash := ipHash("1.2.3.4")
+
+	rr := createRequestAndServe("GET", "/boards/b/thread?no=0", nil, requestCreatorForm)
+	if strings.Contains(rr.Body.String(), "ip_hash") {
+		t.Errorf("public thread shows IP hashes: %s", rr.Body.String())
+	}
+	rr = createRequestAndServe("GET", "/boards/b/thread/all", nil, requestCreatorForm)
+	if strings.Contains(rr.Body.String(), "ip_hash") {
+		t.Errorf("public page shows IP hashes: %s", rr.Body.String())
+	}
+	rr = createRequestAndServe("GET", "/boards/b/thread?no=0", nil, requestCreatorWithToken(token("bob", auth.Moderator)))
+	var got boardResponse
+	_ = json.Unmarshal(rr.Body.Bytes(), &got)
+	if got.Thread.IPHash != hash || got.Thread.Replies[0].IPHash != ipHash("5.6.7.8") {
+		t.Errorf("moderator's thread = %+v, want IP hashes", got.Thread)
+	}
+
+	rr = createRequestAndServe("GET", "/admin/posts?hash="+hash, nil, requestCreatorWithToken(token("bob", auth.Moderator)))
+	checkStatusCode(rr.Code, http.StatusOK, t)
+	var posts posterResponse
+	_ = json.Unmarshal(rr.Body.Bytes(), &posts)
+	if len(posts.Posts) != 2 || posts.Posts[0].Board != "/b/" || posts.Posts[1].Board != "/obj/" {
+		t.Errorf("handler returned %+v, want the threads on both boards newest first", posts.Posts)
+	}
+	rr = createRequestAndServe("GET", "/admin/posts?hash="+hash, nil, requestCreatorWithToken(token("bob", auth.Janitor)))
+	checkStatusCode(rr.Code, http.StatusForbidden, t)
+}
+
 // Test Utilities
 var h = handler()
 
+// Serves the boards from in memory DBs, with the first board as the default board, and no bans.
+func useMemoryBoards(IDs ...string) {
+	defaultBoardID = IDs[0]
+	boards = make(map[string]*board.Store)
+	for _, ID := range IDs {
+		db := data.NewMemoryDB()
+		boards[ID] = board.NewStore(ID, board.Settings{}, db, db)
+	}
+	db := data.NewMemoryDB()
+	fileBans = media.NewBanList(db, db)
+	imageBans = media.NewImageBanList(db, db)
+	limiter = ratelimit.NewLimiter(db)
+	posterBans = ban.NewStore(db, db)
+	mediaLocks = media.NewLocks(db)
+}
+
 func createRequestAndServe(method string, hitEndpoint string, params io.Reader, requestCreator func(string, string, io.Reader) *http.Request) *httptest.ResponseRecorder {
 	req := requestCreator(method, hitEndpoint, params)
 	rr := httptest.NewRecorder()
@@ -35,6 +754,48 @@ func createRequestAndServe(method string, hitEndpoint string, params io.Reader,
 	return rr
 }
 
+// Records the media moved, moving it into the group in name only.
+type movingRepo struct {
+	data.MediaRepo
+	moved []string
+}
+
+func (r *movingRepo) Move(URI string, group string) (string, error) {
+	r.moved = append(r.moved, URI)
+	return group + "/" + URI, nil
+}
+
+// Stores media in a temporary directory for the test, returning the directory.
+func useLocalMedia(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "alice")
+	if err != nil {
+		t.Fatalf("could not create media directory: %v", err)
+	}
+	mediaRepo = data.NewLocalRepo(dir)
+	return dir
+}
+
+// Authenticates with the test key and an admin, alice, whose password is "insecure".
+func useAuthenticator() {
+	hash := "$2a$10$7mwDl2AApzQhF5fGaOOni.7IyMCCLBN3WhV1WH3ezViuxHrCLpLmS" // insecure
+	authenticator = auth.NewAuthenticator(tokenKey, map[string]auth.User{"alice": {Password: hash, Role: auth.Admin}}, time.Hour)
+}
+
+// Returns a token signed with the test key for the user.
+func token(username string, role auth.Role) string {
+	claims := auth.Claims{Role: role, StandardClaims: jwt.StandardClaims{Subject: username, ExpiresAt: time.Now().Add(time.Hour).Unix()}}
+	signed, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(tokenKey)
+	return signed
+}
+
+func requestCreatorWithToken(token string) func(string, string, io.Reader) *http.Request {
+	return func(method, url string, body io.Reader) *http.Request {
+		req := requestCreatorForm(method, url, body)
+		req.Header.Add("Authorization", "Bearer "+token)
+		return req
+	}
+}
+
 func requestCreatorForm(method, url string, body io.Reader) *http.Request {
 	req, _ := http.NewRequest(method, url, body)
 	if body != nil && method == "POST" {
@@ -43,6 +804,43 @@ func requestCreatorForm(method, url string, body io.Reader) *http.Request {
 	return req
 }
 
+// Creates multipart requests from the IP address.
+func requestCreatorFrom(ip string) func(string, string, io.Reader) *http.Request {
+	return func(method, url string, body io.Reader) *http.Request {
+		req := requestCreatorMultipart(method, url, body)
+		req.RemoteAddr = ip + ":1234"
+		return req
+	}
+}
+
+// Boundary of the forms created by multipartForm.
+const boundary = "alice"
+
+// Creates multipart requests from the address httptest uses, as posts are refused when the client's address is invalid.
+func requestCreatorMultipart(method, url string, body io.Reader) *http.Request {
+	req, _ := http.NewRequest(method, url, body)
+	req.RemoteAddr = "192.0.2.1:1234"
+	req.Header.Add("Content-Type", "multipart/form-data; boundary="+boundary)
+	return req
+}
+
+// Returns a multipart form with the fields and an image file.
+func multipartForm(t *testing.T, fields map[string]string, filename string, file []byte) io.Reader {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	_ = form.SetBoundary(boundary)
+	for name, value := range fields {
+		_ = form.WriteField(name, value)
+	}
+	part, err := form.CreateFormFile("image", filename)
+	if err != nil {
+		t.Fatalf("could not create form: %v", err)
+	}
+	_, _ = part.Write(file)
+	_ = form.Close()
+	return &body
+}
+
 func checkStatusCode(got, expected int, t *testing.T) {
 	// Check the status code is what we expect.
 	if got != expected {
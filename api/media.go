@@ -0,0 +1,169 @@
+package main
+
+import (
+	"encoding/json"
+	"github.com/alice-ws/alice/media"
+	"github.com/julienschmidt/httprouter"
+	"github.com/spf13/viper"
+	"log"
+	"net/http"
+	"time"
+)
+
+type mediaResponse struct {
+	Status       string   `json:"status"`
+	Unreferenced []string `json:"unreferenced"`
+	Removed      int      `json:"removed"`
+}
+
+// Returns the media in the image group that is not used by a post on any board or in any archive.
+// Media newer than the grace period is skipped, as its post may not have been stored yet.
+func unreferencedMedia(grace time.Duration) ([]string, error) {
+	referenced := make(map[string]bool)
+	for _, store := range boards {
+		threads, err := store.GetAllThreads()
+		if err != nil {
+			return nil, err
+		}
+		archived, err := store.GetArchivedThreads()
+		if err != nil {
+			return nil, err
+		}
+		threads = append(threads, archived...)
+		for _, t := range threads {
+			for _, p := range t.Posts() {
+				for _, URI := range p.Media() {
+					referenced[URI] = true
+				}
+			}
+		}
+	}
+
+	all, err := mediaRepo.List(dependencyManagement.ImageGroup())
+	if err != nil {
+		return nil, err
+	}
+
+	unreferenced := make([]string, 0)
+	for _, URI := range all {
+		if referenced[URI] {
+			continue
+		}
+		info, err := mediaRepo.Stat(URI)
+		if err != nil || time.Since(info.Modified) < grace {
+			continue
+		}
+		unreferenced = append(unreferenced, URI)
+	}
+	return unreferenced, nil
+}
+
+// Removes media that is not used by any post. Returns the media found and how many were removed.
+func reconcileMedia() ([]string, int, error) {
+	unreferenced, err := unreferencedMedia(viper.GetDuration("media.reconcile.grace"))
+	if err != nil {
+		return nil, 0, err
+	}
+
+	removed := 0
+	for _, URI := range unreferenced {
+		if err := mediaRepo.Delete(URI); err != nil {
+			log.Printf("Error removing unreferenced media %s: %v", URI, err)
+			continue
+		}
+		removed++
+	}
+	log.Printf("Removed %d of %d unreferenced media", removed, len(unreferenced))
+	return unreferenced, removed, nil
+}
+
+// Reconciles media on every interval. Does nothing if the interval is not positive.
+func scheduleMediaReconciliation(interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	for range time.Tick(interval) {
+		if _, _, err := reconcileMedia(); err != nil {
+			log.Printf("Error reconciling media: %v", err)
+		}
+	}
+}
+
+func getUnreferencedMediaHandler(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
+	unreferenced, err := unreferencedMedia(viper.GetDuration("media.reconcile.grace"))
+	if err != nil {
+		w.WriteHeader(http.StatusFailedDependency)
+		_ = json.NewEncoder(w).Encode(mediaResponse{Status: "FAILURE"})
+		return
+	}
+
+	addHeaders(w)
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(mediaResponse{Status: "SUCCESS", Unreferenced: unreferenced})
+}
+
+func deleteUnreferencedMediaHandler(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	unreferenced, removed, err := reconcileMedia()
+	if err != nil {
+		w.WriteHeader(http.StatusFailedDependency)
+		_ = json.NewEncoder(w).Encode(mediaResponse{Status: "FAILURE"})
+		return
+	}
+
+	logModeration(r, "Removed %d unreferenced media", removed)
+	addHeaders(w)
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(mediaResponse{Status: "SUCCESS", Unreferenced: unreferenced, Removed: removed})
+}
+
+type fileBanResponse struct {
+	Status string          `json:"status"`
+	Bans   []media.FileBan `json:"bans"`
+}
+
+// Returns the ban lists from when the handlers are called, as the lists are created once the DB is available.
+func bannedFiles() *media.BanList  { return fileBans }
+func bannedImages() *media.BanList { return imageBans }
+
+func getBansHandler(list func() *media.BanList) httprouter.Handle {
+	return func(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
+		bans, err := list().List()
+		if err != nil {
+			w.WriteHeader(http.StatusFailedDependency)
+			_ = json.NewEncoder(w).Encode(fileBanResponse{Status: "FAILURE"})
+			return
+		}
+
+		addHeaders(w)
+		w.WriteHeader(http.StatusOK)
+		_ = json.NewEncoder(w).Encode(fileBanResponse{Status: "SUCCESS", Bans: bans})
+	}
+}
+
+func addBanHandler(list func() *media.BanList) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		ban, err := list().Ban(r.FormValue("hash"), r.FormValue("reason"))
+		if badRequest(err, w) {
+			return
+		}
+
+		logModeration(r, "Banned %s: %s", ban.Hash, ban.Reason)
+		addHeaders(w)
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(fileBanResponse{Status: "SUCCESS", Bans: []media.FileBan{ban}})
+	}
+}
+
+func deleteBanHandler(list func() *media.BanList) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		hash := r.URL.Query().Get("hash")
+		if notFound(list().Unban(hash), w) {
+			return
+		}
+
+		logModeration(r, "Unbanned %s", hash)
+		addHeaders(w)
+		w.WriteHeader(http.StatusOK)
+		_ = json.NewEncoder(w).Encode(fileBanResponse{Status: "SUCCESS", Bans: []media.FileBan{}})
+	}
+}
@@ -0,0 +1,105 @@
+package media
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/alice-ws/alice/data"
+	"time"
+)
+
+// FileBan stops a file, identified by its hash, being uploaded to any board.
+type FileBan struct {
+	Hash   string    `json:"hash"`
+	Reason string    `json:"reason"`
+	Banned time.Time `json:"banned"`
+}
+
+func (b FileBan) String() string {
+	bytes, _ := json.Marshal(b)
+	return string(bytes)
+}
+
+// BanList is a list of files moderators have banned across all boards.
+type BanList struct {
+	// Prefix of the keys of the bans in the DB
+	name string
+	// Checks a hash is in the form the list holds
+	valid func(hash string) error
+	db    data.KeyValueDB
+	bans  data.OrderedDB
+}
+
+// NewBanList returns the list of files banned by their exact hash.
+func NewBanList(db data.KeyValueDB, bans data.OrderedDB) *BanList {
+	return &BanList{name: "bannedfile", valid: notEmpty, db: db, bans: bans}
+}
+
+// NewImageBanList returns the list of images banned by their perceptual hash.
+func NewImageBanList(db data.KeyValueDB, bans data.OrderedDB) *BanList {
+	return &BanList{name: "bannedimage", valid: validPerceptualHash, db: db, bans: bans}
+}
+
+// Returns key for a ban that is stored in the DB
+func (l *BanList) banKey(hash string) string {
+	return l.name + ":" + hash
+}
+
+// Returns key for the ordered set of banned hashes, scored by when they were banned
+func (l *BanList) listKey() string {
+	return l.name + "s"
+}
+
+func notEmpty(hash string) error {
+	if hash == "" {
+		return errors.New("no hash given")
+	}
+	return nil
+}
+
+// Ban adds the file with the hash to the ban list, replacing the reason if it was already banned.
+func (l *BanList) Ban(hash, reason string) (FileBan, error) {
+	if err := l.valid(hash); err != nil {
+		return FileBan{}, err
+	}
+	ban := FileBan{Hash: hash, Reason: reason, Banned: time.Now()}
+	err := l.db.Set(data.NewKeyValuePair(l.banKey(hash), ban.String()))
+	if err != nil {
+		return FileBan{}, err
+	}
+	return ban, l.bans.SetOrdered(data.NewKeyValuePair(l.listKey(), hash), int(ban.Banned.UnixNano()))
+}
+
+// Unban removes the file with the hash from the ban list.
+func (l *BanList) Unban(hash string) error {
+	if !l.IsBanned(hash) {
+		return errors.New("file " + hash + " is not banned")
+	}
+	err := l.db.Remove(l.banKey(hash))
+	if err != nil {
+		return err
+	}
+	return l.bans.RemoveOrdered(data.NewKeyValuePair(l.listKey(), hash))
+}
+
+// IsBanned returns whether the file with the hash is banned.
+func (l *BanList) IsBanned(hash string) bool {
+	_, err := l.db.Get(l.banKey(hash))
+	return err == nil
+}
+
+// List returns the banned files, most recently banned first.
+func (l *BanList) List() ([]FileBan, error) {
+	bans := make([]FileBan, 0)
+	for _, hash := range l.bans.GetAllOrderedByScore(l.listKey()) {
+		banString, err := l.db.Get(l.banKey(hash))
+		if err != nil {
+			return nil, errors.New("error getting banned files")
+		}
+		var ban FileBan
+		if err := json.Unmarshal([]byte(banString), &ban); err != nil {
+			return nil, errors.New("cannot parse json" + err.Error())
+		}
+		bans = append(bans, ban)
+	}
+	return bans, nil
+}
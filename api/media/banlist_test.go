@@ -0,0 +1,37 @@
+package media
+
+import (
+	"github.com/alice-ws/alice/data"
+	"testing"
+)
+
+func TestBanList(t *testing.T) {
+	db := data.NewMemoryDB()
+	bans := NewBanList(db, db)
+
+	_, _ = bans.Ban("a", "spam")
+	_, _ = bans.Ban("b", "gore")
+
+	if !bans.IsBanned("a") || !bans.IsBanned("b") || bans.IsBanned("c") {
+		t.Errorf("expected a and b to be the only banned files")
+	}
+	list, err := bans.List()
+	if err != nil || len(list) != 2 || list[0].Hash != "b" || list[1].Reason != "spam" {
+		t.Errorf("List() = %v, %v, want b then a", list, err)
+	}
+
+	if err := bans.Unban("a"); err != nil || bans.IsBanned("a") {
+		t.Errorf("Unban() error = %v, expected a to be unbanned", err)
+	}
+	if err := bans.Unban("a"); err == nil {
+		t.Errorf("Unban() expected an error for a file that is not banned")
+	}
+}
+
+func TestBanList_Ban_noHash(t *testing.T) {
+	db := data.NewMemoryDB()
+
+	if _, err := NewBanList(db, db).Ban("", "spam"); err == nil {
+		t.Errorf("Ban() expected an error without a hash")
+	}
+}
@@ -0,0 +1,11 @@
+package media
+
+import "testing"
+
+func TestHash(t *testing.T) {
+	want := "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"
+
+	if got := Hash([]byte("hello")); got != want {
+		t.Errorf("Hash() = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,65 @@
+package media
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"github.com/alice-ws/alice/data"
+	"time"
+)
+
+// How long a lock is held before it expires, so a replica that stops while holding one does not keep the media locked
+const lockExpiry = 30 * time.Second
+
+// How often a held lock is tried again
+const lockRetry = 10 * time.Millisecond
+
+// ErrLocked is returned when a lock is still held by someone else after waiting for it.
+var ErrLocked = errors.New("media is locked")
+
+// Locks are held on stored media while it is reused for a new post or checked for references and removed,
+// so media is not removed between being reused and the new post referencing it.
+// Locks are kept in the DB so they hold across every replica of the API.
+type Locks struct {
+	db data.KeyValueDB
+}
+
+func NewLocks(db data.KeyValueDB) *Locks {
+	return &Locks{db: db}
+}
+
+// Returns key for the lock on the media
+func lockKey(URI string) string {
+	return "medialock:" + URI
+}
+
+// Lock waits up to the timeout for the lock on the media, returning a function that releases it.
+func (l *Locks) Lock(URI string, timeout time.Duration) (func(), error) {
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		return nil, err
+	}
+	kv := data.NewKeyValuePair(lockKey(URI), hex.EncodeToString(token))
+
+	deadline := time.Now().Add(timeout)
+	for {
+		locked, err := l.db.SetIfAbsent(kv, lockExpiry)
+		if err != nil {
+			return nil, err
+		}
+		if locked {
+			break
+		}
+		if time.Now().After(deadline) {
+			return nil, ErrLocked
+		}
+		time.Sleep(lockRetry)
+	}
+
+	return func() {
+		// A lock that expired may have been taken by someone else, whose lock is left alone
+		if held, err := l.db.Get(kv.Key()); err == nil && held == kv.String() {
+			_ = l.db.Remove(kv.Key())
+		}
+	}, nil
+}
@@ -0,0 +1,27 @@
+package media
+
+import (
+	"github.com/alice-ws/alice/data"
+	"testing"
+	"time"
+)
+
+func TestLocks_Lock(t *testing.T) {
+	locks := NewLocks(data.NewMemoryDB())
+
+	release, err := locks.Lock("images/a.png", 0)
+	if err != nil {
+		t.Fatalf("Lock() error = %v", err)
+	}
+	if _, err := locks.Lock("images/a.png", 20*time.Millisecond); err != ErrLocked {
+		t.Errorf("Lock() error = %v, want %v while the media is locked", err, ErrLocked)
+	}
+	if _, err := locks.Lock("images/b.png", 0); err != nil {
+		t.Errorf("Lock() error = %v, want other media to be unlocked", err)
+	}
+
+	release()
+	if _, err := locks.Lock("images/a.png", 0); err != nil {
+		t.Errorf("Lock() error = %v after the lock was released", err)
+	}
+}
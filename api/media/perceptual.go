@@ -0,0 +1,72 @@
+package media
+
+import (
+	"errors"
+	"fmt"
+	"golang.org/x/image/draw"
+	"image"
+	"math/bits"
+	"strconv"
+)
+
+// PerceptualHash returns the difference hash (dHash) of the image as 16 hex characters.
+// The image is shrunk to 9x8 in greyscale and each bit records whether a pixel is brighter than the one to its right,
+// so images that look alike have hashes that differ in few bits even after being re-encoded or resized.
+func PerceptualHash(img image.Image) string {
+	grey := image.NewGray(image.Rect(0, 0, 9, 8))
+	draw.ApproxBiLinear.Scale(grey, grey.Bounds(), img, img.Bounds(), draw.Src, nil)
+
+	var hash uint64
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			hash <<= 1
+			if grey.GrayAt(x, y).Y > grey.GrayAt(x+1, y).Y {
+				hash |= 1
+			}
+		}
+	}
+	return fmt.Sprintf("%016x", hash)
+}
+
+// Distance returns the number of bits that differ between the perceptual hashes.
+func Distance(a, b string) (int, error) {
+	x, err := parsePerceptualHash(a)
+	if err != nil {
+		return 0, err
+	}
+	y, err := parsePerceptualHash(b)
+	if err != nil {
+		return 0, err
+	}
+	return bits.OnesCount64(x ^ y), nil
+}
+
+// Similar returns the ban of an image within the threshold distance of the perceptual hash, if there is one.
+func (l *BanList) Similar(hash string, threshold int) (FileBan, bool, error) {
+	bans, err := l.List()
+	if err != nil {
+		return FileBan{}, false, err
+	}
+	for _, ban := range bans {
+		distance, err := Distance(hash, ban.Hash)
+		if err != nil {
+			return FileBan{}, false, err
+		}
+		if distance <= threshold {
+			return ban, true, nil
+		}
+	}
+	return FileBan{}, false, nil
+}
+
+func parsePerceptualHash(hash string) (uint64, error) {
+	if len(hash) != 16 {
+		return 0, errors.New("perceptual hash " + hash + " is not 16 hex characters")
+	}
+	return strconv.ParseUint(hash, 16, 64)
+}
+
+func validPerceptualHash(hash string) error {
+	_, err := parsePerceptualHash(hash)
+	return err
+}
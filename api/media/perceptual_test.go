@@ -0,0 +1,79 @@
+package media
+
+import (
+	"bytes"
+	"github.com/alice-ws/alice/data"
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestPerceptualHash(t *testing.T) {
+	original := pattern(400, 300, false)
+	reencoded, _, _ := image.Decode(bytes.NewReader(encodeJPEGImage(original)))
+
+	tests := []struct {
+		name        string
+		img         image.Image
+		maxDistance int
+		minDistance int
+	}{
+		{name: "same image", img: original, maxDistance: 0},
+		{name: "re-encoded as jpeg", img: reencoded, maxDistance: 4},
+		{name: "resized", img: pattern(123, 92, false), maxDistance: 6},
+		{name: "different image", img: pattern(400, 300, true), minDistance: 20, maxDistance: 64},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			distance, err := Distance(PerceptualHash(original), PerceptualHash(tt.img))
+			if err != nil {
+				t.Fatalf("Distance() error = %v", err)
+			}
+			if distance < tt.minDistance || distance > tt.maxDistance {
+				t.Errorf("distance = %d, want between %d and %d", distance, tt.minDistance, tt.maxDistance)
+			}
+		})
+	}
+}
+
+func TestDistance(t *testing.T) {
+	if got, _ := Distance("00000000000000ff", "000000000000000f"); got != 4 {
+		t.Errorf("Distance() = %d, want 4", got)
+	}
+	for _, invalid := range []string{"", "ff", "zzzzzzzzzzzzzzzz"} {
+		if _, err := Distance(invalid, "0000000000000000"); err == nil {
+			t.Errorf("Distance(%q) expected an error", invalid)
+		}
+	}
+}
+
+func TestBanList_Similar(t *testing.T) {
+	db := data.NewMemoryDB()
+	bans := NewImageBanList(db, db)
+	_, _ = bans.Ban("00000000000000ff", "spam")
+
+	if _, err := bans.Ban("not a hash", "spam"); err == nil {
+		t.Errorf("Ban() expected an error for an invalid perceptual hash")
+	}
+	if ban, banned, err := bans.Similar("000000000000000f", 4); err != nil || !banned || ban.Reason != "spam" {
+		t.Errorf("Similar() = %v, %v, %v, want the ban within the threshold", ban, banned, err)
+	}
+	if _, banned, _ := bans.Similar("000000000000000f", 3); banned {
+		t.Errorf("Similar() found a ban outside the threshold")
+	}
+}
+
+// Returns an image of blocks of varying brightness, or the same blocks mirrored.
+func pattern(width, height int, mirrored bool) image.Image {
+	img := image.NewGray(image.Rect(0, 0, width, height))
+	for x := 0; x < width; x++ {
+		for y := 0; y < height; y++ {
+			bx, by := x*8/width, y*6/height
+			if mirrored {
+				bx = 7 - bx
+			}
+			img.SetGray(x, y, color.Gray{Y: uint8((bx*37 + by*53 + bx*by*11) % 256)})
+		}
+	}
+	return img
+}
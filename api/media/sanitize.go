@@ -0,0 +1,115 @@
+package media
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// JPEG segments holding metadata rather than image data: EXIF and XMP (APP1), application
+// specific segments that are not needed to decode the image, IPTC (APP13) and comments.
+// JFIF (APP0), ICC profiles (APP2) and Adobe colour transforms (APP14) are kept as they affect how the image looks.
+var jpegMetadata = map[byte]bool{
+	0xe1: true, 0xe3: true, 0xe4: true, 0xe5: true, 0xe6: true, 0xe7: true, 0xe8: true,
+	0xe9: true, 0xea: true, 0xeb: true, 0xec: true, 0xed: true, 0xef: true, 0xfe: true,
+}
+
+// PNG chunks holding metadata: text, EXIF and the last modification time.
+var pngMetadata = map[string]bool{
+	"tEXt": true, "zTXt": true, "iTXt": true, "eXIf": true, "tIME": true,
+}
+
+var errTruncated = errors.New("image is truncated")
+
+// StripMetadata returns a copy of the JPEG or PNG image without its metadata, such as EXIF GPS coordinates
+// and camera serial numbers. The image data is copied as is so the image is not re-encoded and loses no quality,
+// though JPEGs lose their EXIF orientation. Other types of media are returned unchanged.
+func StripMetadata(file []byte) ([]byte, error) {
+	switch Detect(file) {
+	case JPEG:
+		return stripJPEG(file)
+	case PNG:
+		return stripPNG(file)
+	}
+	return file, nil
+}
+
+func stripJPEG(file []byte) ([]byte, error) {
+	stripped := make([]byte, 0, len(file))
+	stripped = append(stripped, file[:2]...)
+	for i := 2; ; {
+		if i+2 > len(file) {
+			return nil, errTruncated
+		}
+		if file[i] != 0xff {
+			return nil, errors.New("invalid JPEG marker")
+		}
+		marker := file[i+1]
+		switch {
+		case marker == 0xff:
+			// Padding before a marker
+			i++
+			continue
+		case marker == 0xd9:
+			// Anything after the end of the image, such as the secondary images of an MPO or an appended file, is dropped too
+			return append(stripped, file[i:i+2]...), nil
+		case marker == 0x01, marker >= 0xd0 && marker <= 0xd7:
+			// Markers without a segment
+			stripped = append(stripped, file[i:i+2]...)
+			i += 2
+			continue
+		}
+
+		if i+4 > len(file) {
+			return nil, errTruncated
+		}
+		end := i + 2 + int(binary.BigEndian.Uint16(file[i+2:]))
+		if end < i+4 || end > len(file) {
+			return nil, errTruncated
+		}
+		if marker == 0xda {
+			// The compressed data of the scan follows its header up to the next marker
+			end = scanEnd(file, end)
+		}
+		if !jpegMetadata[marker] {
+			stripped = append(stripped, file[i:end]...)
+		}
+		i = end
+	}
+}
+
+// Returns the index of the first marker after the compressed data of a JPEG scan starting at the index,
+// or the length of the file if there is none. A 0xff in the data is followed by a zero byte, and restart
+// markers are part of the data.
+func scanEnd(file []byte, i int) int {
+	for ; i+1 < len(file); i++ {
+		if file[i] == 0xff && file[i+1] != 0x00 && (file[i+1] < 0xd0 || file[i+1] > 0xd7) {
+			return i
+		}
+	}
+	return len(file)
+}
+
+func stripPNG(file []byte) ([]byte, error) {
+	stripped := make([]byte, 0, len(file))
+	stripped = append(stripped, file[:8]...)
+	for i := 8; ; {
+		if i+8 > len(file) {
+			return nil, errTruncated
+		}
+		length := int(binary.BigEndian.Uint32(file[i:]))
+		chunk := string(file[i+4 : i+8])
+		// Length and type, then data and CRC
+		end := i + 12 + length
+		if length < 0 || end < i || end > len(file) {
+			return nil, errTruncated
+		}
+		if !pngMetadata[chunk] {
+			stripped = append(stripped, file[i:end]...)
+		}
+		if chunk == "IEND" {
+			// Anything after the end of the image is dropped too
+			return stripped, nil
+		}
+		i = end
+	}
+}
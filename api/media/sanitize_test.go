@@ -0,0 +1,107 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"testing"
+)
+
+func TestStripMetadata_jpeg(t *testing.T) {
+	original := encodeJPEG(10, 10)
+	exif := segment(0xe1, []byte("Exif\x00\x00GPS 51.5074 N"))
+	comment := segment(0xfe, []byte("camera serial 1234"))
+	icc := segment(0xe2, []byte("ICC_PROFILE\x00"))
+	withMetadata := join(original[:2], exif, icc, comment, original[2:])
+
+	got, err := StripMetadata(withMetadata)
+
+	if err != nil {
+		t.Fatalf("StripMetadata() error = %v", err)
+	}
+	if bytes.Contains(got, []byte("GPS")) || bytes.Contains(got, []byte("serial")) {
+		t.Errorf("StripMetadata() kept metadata")
+	}
+	if !bytes.Equal(got, join(original[:2], icc, original[2:])) {
+		t.Errorf("StripMetadata() changed the image data")
+	}
+	if _, _, err := image.Decode(bytes.NewReader(got)); err != nil {
+		t.Errorf("stripped image cannot be decoded: %v", err)
+	}
+}
+
+func TestStripMetadata_jpegTrailingData(t *testing.T) {
+	original := encodeJPEG(10, 10)
+	// A second image after the end of the first, as in an MPO from a phone camera, carrying its own EXIF
+	secondary := encodeJPEG(5, 5)
+	secondary = join(secondary[:2], segment(0xe1, []byte("Exif\x00\x00GPS 51.5074 N")), secondary[2:])
+	withTrailing := join(original, secondary, []byte("trailing"))
+
+	got, err := StripMetadata(withTrailing)
+
+	if err != nil {
+		t.Fatalf("StripMetadata() error = %v", err)
+	}
+	if !bytes.Equal(got, original) {
+		t.Errorf("StripMetadata() kept data after the end of the image")
+	}
+}
+
+func TestStripMetadata_png(t *testing.T) {
+	original := encodePNG(10, 10)
+	text := chunk("tEXt", []byte("Comment\x00GPS 51.5074 N"))
+	exif := chunk("eXIf", []byte("MM\x00*serial"))
+	// Chunks go after the header chunk, which is 25 bytes including its length, type and CRC
+	withMetadata := join(original[:33], text, exif, original[33:], []byte("trailing"))
+
+	got, err := StripMetadata(withMetadata)
+
+	if err != nil {
+		t.Fatalf("StripMetadata() error = %v", err)
+	}
+	if !bytes.Equal(got, original) {
+		t.Errorf("StripMetadata() = %q, want the original image", got)
+	}
+}
+
+func TestStripMetadata_otherMedia(t *testing.T) {
+	gif := encodeGIF(1, 1)
+
+	if got, err := StripMetadata(gif); err != nil || !bytes.Equal(got, gif) {
+		t.Errorf("StripMetadata() = %v, %v, want the gif unchanged", got, err)
+	}
+}
+
+func TestStripMetadata_truncated(t *testing.T) {
+	for name, file := range map[string][]byte{
+		"jpeg": join(encodeJPEG(1, 1)[:2], []byte{0xff, 0xe1, 0x10, 0x00}),
+		"png":  encodePNG(1, 1)[:20],
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := StripMetadata(file); err == nil {
+				t.Errorf("StripMetadata() expected an error")
+			}
+		})
+	}
+}
+
+func segment(marker byte, data []byte) []byte {
+	s := []byte{0xff, marker, 0, 0}
+	binary.BigEndian.PutUint16(s[2:], uint16(len(data)+2))
+	return append(s, data...)
+}
+
+func chunk(chunkType string, data []byte) []byte {
+	c := make([]byte, 4, 12+len(data))
+	binary.BigEndian.PutUint32(c, uint32(len(data)))
+	c = append(c, chunkType...)
+	c = append(c, data...)
+	crc := make([]byte, 4)
+	binary.BigEndian.PutUint32(crc, crc32.ChecksumIEEE(c[4:]))
+	return append(c, crc...)
+}
+
+func join(parts ...[]byte) []byte {
+	return bytes.Join(parts, nil)
+}
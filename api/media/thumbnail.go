@@ -0,0 +1,90 @@
+// Package media processes uploaded media before it is stored.
+package media
+
+import (
+	"bytes"
+	"errors"
+	"golang.org/x/image/draw"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+)
+
+// ThumbnailSize is the largest width or height of a thumbnail.
+const ThumbnailSize = 250
+
+// ErrNotImage is returned for media that cannot be decoded as an image, such as video.
+var ErrNotImage = errors.New("media is not an image")
+
+// Thumbnail is a downscaled copy of an image.
+type Thumbnail struct {
+	Data []byte
+	// Ext is the file extension matching the format the thumbnail was encoded in.
+	Ext string
+	// Width and Height of the thumbnail, not of the original image
+	Width  int
+	Height int
+	// PerceptualHash of the image, which is taken from the thumbnail as it looks the same
+	PerceptualHash string
+}
+
+// NewThumbnail decodes the PNG, JPEG or GIF image and scales it down to fit within ThumbnailSize.
+// Only the first frame of an animated GIF is used. JPEGs are thumbnailed as JPEGs and
+// everything else as PNGs so transparency is kept.
+func NewThumbnail(file []byte) (Thumbnail, error) {
+	detected := Detect(file)
+	var decode func(io.Reader) (image.Image, error)
+	switch detected {
+	case PNG:
+		decode = png.Decode
+	case JPEG:
+		decode = jpeg.Decode
+	case GIF:
+		decode = gif.Decode
+	default:
+		return Thumbnail{}, ErrNotImage
+	}
+
+	img, err := decode(bytes.NewReader(file))
+	if err != nil {
+		return Thumbnail{}, errors.New("cannot decode image: " + err.Error())
+	}
+
+	bounds := img.Bounds()
+	scaled := image.NewRGBA(fit(bounds.Dx(), bounds.Dy(), ThumbnailSize))
+	draw.CatmullRom.Scale(scaled, scaled.Bounds(), img, bounds, draw.Src, nil)
+
+	var buf bytes.Buffer
+	ext := ".png"
+	if detected == JPEG {
+		ext = ".jpg"
+		err = jpeg.Encode(&buf, scaled, &jpeg.Options{Quality: 85})
+	} else {
+		err = png.Encode(&buf, scaled)
+	}
+	if err != nil {
+		return Thumbnail{}, errors.New("cannot encode thumbnail: " + err.Error())
+	}
+	return Thumbnail{Data: buf.Bytes(), Ext: ext, Width: scaled.Bounds().Dx(), Height: scaled.Bounds().Dy(), PerceptualHash: PerceptualHash(scaled)}, nil
+}
+
+// Returns the bounds of an image scaled down to fit within a square of the given size, keeping its aspect ratio.
+// Images that already fit are not scaled up.
+func fit(width, height, size int) image.Rectangle {
+	if width <= size && height <= size {
+		return image.Rect(0, 0, width, height)
+	}
+	if width >= height {
+		return image.Rect(0, 0, size, atLeastOne(height*size/width))
+	}
+	return image.Rect(0, 0, atLeastOne(width*size/height), size)
+}
+
+func atLeastOne(n int) int {
+	if n < 1 {
+		return 1
+	}
+	return n
+}
@@ -0,0 +1,94 @@
+package media
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+func TestNewThumbnail(t *testing.T) {
+	tests := []struct {
+		name                  string
+		file                  []byte
+		wantExt               string
+		wantWidth, wantHeight int
+	}{
+		{name: "png", file: encodePNG(1000, 500), wantExt: ".png", wantWidth: 250, wantHeight: 125},
+		{name: "jpeg", file: encodeJPEG(500, 1000), wantExt: ".jpg", wantWidth: 125, wantHeight: 250},
+		{name: "gif", file: encodeGIF(400, 400), wantExt: ".png", wantWidth: 250, wantHeight: 250},
+		{name: "small image is not scaled up", file: encodePNG(10, 20), wantExt: ".png", wantWidth: 10, wantHeight: 20},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewThumbnail(tt.file)
+			if err != nil {
+				t.Fatalf("NewThumbnail() error = %v", err)
+			}
+			if got.Ext != tt.wantExt {
+				t.Errorf("NewThumbnail() ext = %s, want %s", got.Ext, tt.wantExt)
+			}
+			thumbnail, _, err := image.Decode(bytes.NewReader(got.Data))
+			if err != nil {
+				t.Fatalf("thumbnail cannot be decoded: %v", err)
+			}
+			if size := thumbnail.Bounds().Size(); size.X != tt.wantWidth || size.Y != tt.wantHeight {
+				t.Errorf("thumbnail is %v, want %dx%d", size, tt.wantWidth, tt.wantHeight)
+			}
+			if got.Width != tt.wantWidth || got.Height != tt.wantHeight {
+				t.Errorf("NewThumbnail() size = %dx%d, want %dx%d", got.Width, got.Height, tt.wantWidth, tt.wantHeight)
+			}
+		})
+	}
+}
+
+func TestNewThumbnail_notImage(t *testing.T) {
+	webm := []byte("\x1a\x45\xdf\xa3webm")
+
+	if _, err := NewThumbnail(webm); err != ErrNotImage {
+		t.Errorf("NewThumbnail() error = %v, want %v", err, ErrNotImage)
+	}
+}
+
+func TestNewThumbnail_corrupt(t *testing.T) {
+	corrupt := encodePNG(10, 10)[:20]
+
+	if _, err := NewThumbnail(corrupt); err == nil || err == ErrNotImage {
+		t.Errorf("NewThumbnail() error = %v, want a decoding error", err)
+	}
+}
+
+func img(width, height int) *image.RGBA {
+	i := image.NewRGBA(image.Rect(0, 0, width, height))
+	for x := 0; x < width; x++ {
+		for y := 0; y < height; y++ {
+			i.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 100, A: 255})
+		}
+	}
+	return i
+}
+
+func encodePNG(width, height int) []byte {
+	var buf bytes.Buffer
+	_ = png.Encode(&buf, img(width, height))
+	return buf.Bytes()
+}
+
+func encodeJPEG(width, height int) []byte {
+	return encodeJPEGImage(img(width, height))
+}
+
+func encodeJPEGImage(i image.Image) []byte {
+	var buf bytes.Buffer
+	_ = jpeg.Encode(&buf, i, nil)
+	return buf.Bytes()
+}
+
+func encodeGIF(width, height int) []byte {
+	var buf bytes.Buffer
+	_ = gif.Encode(&buf, img(width, height), nil)
+	return buf.Bytes()
+}
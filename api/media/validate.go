@@ -0,0 +1,130 @@
+package media
+
+import (
+	"bytes"
+	"image"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Type is the format of media as detected from its contents.
+type Type string
+
+// Media types that can be uploaded
+const (
+	PNG     Type = "png"
+	JPEG    Type = "jpeg"
+	GIF     Type = "gif"
+	WebM    Type = "webm"
+	Unknown Type = ""
+)
+
+// Extensions of each media type
+var extensions = map[string]Type{
+	".png":  PNG,
+	".jpg":  JPEG,
+	".jpeg": JPEG,
+	".gif":  GIF,
+	".webm": WebM,
+}
+
+// Codes identifying why an upload was rejected
+const (
+	FileTooLarge       = "FILE_TOO_LARGE"
+	UnsupportedType    = "UNSUPPORTED_TYPE"
+	TypeMismatch       = "TYPE_MISMATCH"
+	CorruptFile        = "CORRUPT_FILE"
+	DimensionsTooLarge = "DIMENSIONS_TOO_LARGE"
+	BannedFile         = "BANNED_FILE"
+	BannedImage        = "BANNED_IMAGE"
+	Repost             = "REPOST"
+)
+
+// Error is returned when an upload is rejected, with a code for the reason it was rejected.
+type Error struct {
+	Code   string
+	Reason string
+}
+
+func (e *Error) Error() string {
+	return e.Reason
+}
+
+// Limits on the uploads that are accepted.
+type Limits struct {
+	MaxSize   int64
+	MaxWidth  int
+	MaxHeight int
+}
+
+// Info describes validated media.
+type Info struct {
+	Type   Type
+	Width  int
+	Height int
+}
+
+// Detect returns the type of the media from the magic bytes at its start.
+func Detect(file []byte) Type {
+	switch {
+	case bytes.HasPrefix(file, []byte("\x89PNG\r\n\x1a\n")):
+		return PNG
+	case bytes.HasPrefix(file, []byte("\xff\xd8\xff")):
+		return JPEG
+	case bytes.HasPrefix(file, []byte("GIF87a")), bytes.HasPrefix(file, []byte("GIF89a")):
+		return GIF
+	case isWebM(file):
+		return WebM
+	}
+	return Unknown
+}
+
+// Validate checks the upload is the type its file name claims and within the limits.
+// Images are checked for their dimensions without decoding them, so large images are rejected cheaply.
+func Validate(file []byte, filename string, limits Limits) (Info, error) {
+	if int64(len(file)) > limits.MaxSize {
+		return Info{}, &Error{FileTooLarge, "file " + filename + " is larger than " + strconv.FormatInt(limits.MaxSize, 10) + " bytes"}
+	}
+
+	claimed, ok := extensions[strings.ToLower(filepath.Ext(filename))]
+	if !ok {
+		return Info{}, &Error{UnsupportedType, "file " + filename + " is not a supported type"}
+	}
+	detected := Detect(file)
+	if detected != claimed {
+		return Info{}, &Error{TypeMismatch, "file " + filename + " is not a " + string(claimed)}
+	}
+	if detected == WebM {
+		return Info{Type: WebM}, nil
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(file))
+	if err != nil {
+		return Info{}, &Error{CorruptFile, "file " + filename + " cannot be read: " + err.Error()}
+	}
+	if config.Width > limits.MaxWidth || config.Height > limits.MaxHeight {
+		return Info{}, &Error{DimensionsTooLarge, "image " + filename + " is larger than " + strconv.Itoa(limits.MaxWidth) + "x" + strconv.Itoa(limits.MaxHeight)}
+	}
+	return Info{Type: detected, Width: config.Width, Height: config.Height}, nil
+}
+
+// Returns whether the file starts with an EBML header declaring the webm document type.
+func isWebM(file []byte) bool {
+	if !bytes.HasPrefix(file, []byte("\x1a\x45\xdf\xa3")) {
+		return false
+	}
+	// The DocType element is within the first few bytes of the header
+	header := file
+	if len(header) > 64 {
+		header = header[:64]
+	}
+	i := bytes.Index(header, []byte("\x42\x82"))
+	if i < 0 || i+2 >= len(header) {
+		return false
+	}
+	// The element's size is a one byte variable length integer for short document types
+	size := int(header[i+2] &^ 0x80)
+	start := i + 3
+	return start+size <= len(header) && string(header[start:start+size]) == "webm"
+}
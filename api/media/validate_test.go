@@ -0,0 +1,71 @@
+package media
+
+import (
+	"testing"
+)
+
+var limits = Limits{MaxSize: 1 << 20, MaxWidth: 100, MaxHeight: 100}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		file     []byte
+		filename string
+		want     Info
+		wantCode string
+	}{
+		{name: "png", file: encodePNG(100, 50), filename: "image.png", want: Info{Type: PNG, Width: 100, Height: 50}},
+		{name: "jpeg", file: encodeJPEG(20, 30), filename: "image.JPG", want: Info{Type: JPEG, Width: 20, Height: 30}},
+		{name: "gif", file: encodeGIF(10, 10), filename: "image.gif", want: Info{Type: GIF, Width: 10, Height: 10}},
+		{name: "webm", file: webm(), filename: "video.webm", want: Info{Type: WebM}},
+		{name: "too large", file: make([]byte, limits.MaxSize+1), filename: "image.png", wantCode: FileTooLarge},
+		{name: "unsupported extension", file: encodePNG(10, 10), filename: "image.exe", wantCode: UnsupportedType},
+		{name: "renamed executable", file: []byte("MZ\x90\x00\x03"), filename: "x.png", wantCode: TypeMismatch},
+		{name: "jpeg named as png", file: encodeJPEG(10, 10), filename: "image.png", wantCode: TypeMismatch},
+		{name: "matroska named as webm", file: []byte("\x1a\x45\xdf\xa3\x42\x82\x88matroska"), filename: "video.webm", wantCode: TypeMismatch},
+		{name: "truncated png", file: encodePNG(10, 10)[:12], filename: "image.png", wantCode: CorruptFile},
+		{name: "too wide", file: encodePNG(101, 10), filename: "image.png", wantCode: DimensionsTooLarge},
+		{name: "too tall", file: encodeGIF(10, 101), filename: "image.gif", wantCode: DimensionsTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Validate(tt.file, tt.filename, limits)
+			if tt.wantCode != "" {
+				if e, ok := err.(*Error); !ok || e.Code != tt.wantCode {
+					t.Errorf("Validate() error = %v, want code %s", err, tt.wantCode)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("Validate() = %v, %v, want %v", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name string
+		file []byte
+		want Type
+	}{
+		{name: "png", file: encodePNG(1, 1), want: PNG},
+		{name: "jpeg", file: encodeJPEG(1, 1), want: JPEG},
+		{name: "gif", file: encodeGIF(1, 1), want: GIF},
+		{name: "webm", file: webm(), want: WebM},
+		{name: "empty", file: []byte{}, want: Unknown},
+		{name: "text", file: []byte("hello"), want: Unknown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Detect(tt.file); got != tt.want {
+				t.Errorf("Detect() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// Returns the start of a WebM's EBML header: the EBML version elements followed by the webm DocType.
+func webm() []byte {
+	return []byte("\x1a\x45\xdf\xa3\x9f\x42\x86\x81\x01\x42\xf7\x81\x01\x42\xf2\x81\x04\x42\xf3\x81\x08\x42\x82\x84webm\x42\x87\x81\x04")
+}
@@ -10,6 +10,7 @@ import (
 	"path"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -63,7 +64,100 @@ func (m MinioClient) Store(obj io.Reader, bucket, name string, size int64) (stri
 	}
 
 	log.Printf("Storing image %s in bucket %s", name, bucket)
-	return bucket + "/" + name, nil
+	return m.URI(bucket, name), nil
+}
+
+// URI returns the URI of the object in the bucket, in the form bucket/name.
+func (m MinioClient) URI(bucket, name string) string {
+	return bucket + "/" + name
+}
+
+// Delete removes the object. URIs are in the form bucket/name as returned by Store.
+func (m MinioClient) Delete(URI string) error {
+	bucket, name, err := splitURI(URI)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Removing image %s from bucket %s", name, bucket)
+	return m.client.RemoveObject(bucket, name)
+}
+
+func (m MinioClient) Exists(URI string) (bool, error) {
+	_, err := m.Stat(URI)
+	if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (m MinioClient) Stat(URI string) (data.MediaInfo, error) {
+	bucket, name, err := splitURI(URI)
+	if err != nil {
+		return data.MediaInfo{}, err
+	}
+
+	info, err := m.client.StatObject(bucket, name, minio.StatObjectOptions{})
+	if err != nil {
+		return data.MediaInfo{}, err
+	}
+	return data.MediaInfo{
+		URI:         URI,
+		Size:        info.Size,
+		ContentType: info.ContentType,
+		Modified:    info.LastModified,
+	}, nil
+}
+
+// List returns the URIs of every object in the bucket.
+func (m MinioClient) List(bucket string) ([]string, error) {
+	done := make(chan struct{})
+	defer close(done)
+
+	var URIs []string
+	for object := range m.client.ListObjectsV2(bucket, "", true, done) {
+		if object.Err != nil {
+			return nil, object.Err
+		}
+		URIs = append(URIs, bucket+"/"+object.Key)
+	}
+	return URIs, nil
+}
+
+// Move copies the object into the bucket, creating the bucket if needed, and then removes the original.
+func (m MinioClient) Move(URI string, bucket string) (string, error) {
+	srcBucket, name, err := splitURI(URI)
+	if err != nil {
+		return "", err
+	}
+	if srcBucket == bucket {
+		return URI, nil
+	}
+
+	err = m.createBucket(bucket)
+	if err != nil {
+		return "", err
+	}
+	dst, err := minio.NewDestinationInfo(bucket, name, nil, nil)
+	if err != nil {
+		return "", err
+	}
+	err = m.client.CopyObject(dst, minio.NewSourceInfo(srcBucket, name, nil))
+	if err != nil {
+		return "", err
+	}
+
+	log.Printf("Moved image %s from bucket %s to %s", name, srcBucket, bucket)
+	return bucket + "/" + name, m.client.RemoveObject(srcBucket, name)
+}
+
+// Returns the bucket and object name of the URI.
+func splitURI(URI string) (bucket, name string, err error) {
+	parts := strings.SplitN(URI, "/", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", errors.New("invalid object URI " + URI)
+	}
+	return parts[0], parts[1], nil
 }
 
 func (m MinioClient) GenerateUniqueName(fileName string) string {
@@ -83,12 +177,12 @@ func (m MinioClient) createBucket(name string) error {
 		log.Printf("Successfully created %s\n", name)
 	}
 
-	policy := ` {"Version":"2012-10-17","Statement":[{"Effect":"Allow","Principal":{"AWS":["*"]},"Action":["s3:GetBucketLocation","s3:ListBucket"],"Resource":["arn:aws:s3:::images"]},{"Effect":"Allow","Principal":{"AWS":["*"]},"Action":["s3:GetObject"],"Resource":["arn:aws:s3:::images/*"]}]}`
-	err = m.client.SetBucketPolicy(m.defaultBucket, policy)
-	setPolicy, _ := m.client.GetBucketPolicy(m.defaultBucket)
-	log.Printf("Current %s policy: %v", m.defaultBucket, setPolicy)
+	policy := ` {"Version":"2012-10-17","Statement":[{"Effect":"Allow","Principal":{"AWS":["*"]},"Action":["s3:GetBucketLocation","s3:ListBucket"],"Resource":["arn:aws:s3:::` + name + `"]},{"Effect":"Allow","Principal":{"AWS":["*"]},"Action":["s3:GetObject"],"Resource":["arn:aws:s3:::` + name + `/*"]}]}`
+	err = m.client.SetBucketPolicy(name, policy)
+	setPolicy, _ := m.client.GetBucketPolicy(name)
+	log.Printf("Current %s policy: %v", name, setPolicy)
 	if err != nil {
-		log.Printf("Error making default bucket %s available as download host: %v", m.defaultBucket, err)
+		log.Printf("Error making bucket %s available as download host: %v", name, err)
 		return err
 	}
 	return nil
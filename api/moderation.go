@@ -0,0 +1,151 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/alice-ws/alice/auth"
+	"github.com/alice-ws/alice/board"
+	"github.com/julienschmidt/httprouter"
+	"log"
+	"net/http"
+	"strconv"
+)
+
+func deleteThreadHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	store, ok := storeFor(w, ps)
+	if !ok {
+		return
+	}
+
+	threadNo := r.URL.Query().Get("no")
+	if threadNo == "" {
+		badRequest(errors.New("no thread given to delete"), w)
+		return
+	}
+
+	t, err := store.DeleteThread(threadNo)
+	if notFound(err, w) {
+		return
+	}
+
+	logModeration(r, "Deleted thread %s on board %s", threadNo, store.ID)
+	removeMedia(t.Posts()...)
+	deleted(w, threadNo)
+}
+
+func deletePostHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	store, ok := storeFor(w, ps)
+	if !ok {
+		return
+	}
+
+	threadNo, no, err := postToModerate(r)
+	if badRequest(err, w) {
+		return
+	}
+
+	p, err := store.DeletePost(threadNo, no)
+	if notFound(err, w) {
+		return
+	}
+
+	logModeration(r, "Deleted post %d in thread %s on board %s", no, threadNo, store.ID)
+	removeMedia(p)
+	deleted(w, p.Key())
+}
+
+func deleteFileHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	store, ok := storeFor(w, ps)
+	if !ok {
+		return
+	}
+
+	threadNo, no, err := postToModerate(r)
+	if badRequest(err, w) {
+		return
+	}
+
+	p, err := store.DeleteFile(threadNo, no)
+	if notFound(err, w) {
+		return
+	}
+
+	logModeration(r, "Deleted file of post %d in thread %s on board %s", no, threadNo, store.ID)
+	removeMedia(p)
+	deleted(w, p.Key())
+}
+
+// Returns the thread and post numbers from the request query
+func postToModerate(r *http.Request) (threadNo string, no uint64, err error) {
+	threadNo = r.URL.Query().Get("threadNo")
+	if threadNo == "" {
+		return "", 0, errors.New("no thread given")
+	}
+	no, err = strconv.ParseUint(r.URL.Query().Get("no"), 10, 64)
+	if err != nil {
+		return "", 0, errors.New("invalid post no: " + err.Error())
+	}
+	return threadNo, no, nil
+}
+
+// Removes the media of the posts from the media repository, logging any failures.
+// Media is kept while another post, on any board or in any archive, uses it.
+func removeMedia(posts ...board.Post) {
+	removed := make(map[string]bool)
+	for _, p := range posts {
+		for _, URI := range p.Media() {
+			if removed[URI] {
+				continue
+			}
+			removed[URI] = true
+			if err := removeUnreferencedMedia(URI); err != nil {
+				log.Printf("Error removing media %s of post %d: %v", URI, p.No, err)
+			}
+		}
+	}
+}
+
+// Removes the media unless a post uses it. The media is locked so it cannot be reused for a new post meanwhile.
+func removeUnreferencedMedia(URI string) error {
+	release, err := mediaLocks.Lock(URI, mediaLockTimeout)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	if mediaReferences(URI) > 0 {
+		return nil
+	}
+	return mediaRepo.Delete(URI)
+}
+
+// Returns the number of posts on every board and in every archive using the media.
+func mediaReferences(URI string) int64 {
+	var references int64
+	for _, store := range boards {
+		references += store.MediaReferences(URI)
+	}
+	return references
+}
+
+// Logs the moderation action along with the user that performed it.
+func logModeration(r *http.Request, format string, v ...interface{}) {
+	claims, _ := auth.ClaimsFrom(r.Context())
+	log.Printf("%s (%s): "+format, append([]interface{}{claims.Username(), claims.Role}, v...)...)
+}
+
+func notFound(err error, w http.ResponseWriter) bool {
+	if err != nil {
+		log.Printf("Error: %s", err.Error())
+		w.WriteHeader(http.StatusNotFound)
+		_ = json.NewEncoder(w).Encode(boardResponse{Status: "FAILURE"})
+		return true
+	}
+	return false
+}
+
+func deleted(w http.ResponseWriter, no string) {
+	addHeaders(w)
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(boardResponse{Status: "SUCCESS", No: no})
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"github.com/alice-ws/alice/auth"
+	"github.com/alice-ws/alice/board"
+	"github.com/julienschmidt/httprouter"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+type posterResponse struct {
+	Status string             `json:"status"`
+	Posts  []board.PosterPost `json:"posts"`
+}
+
+// Returns the hash of the IP address keyed with the secret salt, so posts from the same address can be
+// found without storing the address. The salt stops the hashes being reversed by hashing every address.
+func ipHash(ip string) string {
+	mac := hmac.New(sha256.New, ipSalt)
+	_, _ = mac.Write([]byte(ip))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Returns whether the request has a valid bearer token for a moderator, who can see posters' IP hashes.
+func moderating(r *http.Request) bool {
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if token == "" {
+		return false
+	}
+	claims, err := authenticator.Validate(token)
+	return err == nil && claims.Role.Includes(auth.Moderator)
+}
+
+// Lists the posts on every board made from the IP hash, newest first.
+func getPosterPostsHandler(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	hash := r.URL.Query().Get("hash")
+	if hash == "" {
+		badRequest(errors.New("no IP hash given"), w)
+		return
+	}
+
+	posts := make([]board.PosterPost, 0)
+	for _, store := range boards {
+		posts = append(posts, store.PostsByPoster(hash)...)
+	}
+	sort.SliceStable(posts, func(i, j int) bool {
+		return posts[i].Post.Timestamp.After(posts[j].Post.Timestamp)
+	})
+
+	addHeaders(w)
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(posterResponse{Status: "SUCCESS", Posts: posts})
+}
@@ -0,0 +1,55 @@
+// Package ratelimit limits how often clients can take an action, such as posting.
+// Limits are kept in the DB so they hold across every replica of the API.
+package ratelimit
+
+import (
+	"github.com/alice-ws/alice/data"
+	"strconv"
+	"time"
+)
+
+// Limiter records when each client last took each action.
+type Limiter struct {
+	db data.KeyValueDB
+}
+
+func NewLimiter(db data.KeyValueDB) *Limiter {
+	return &Limiter{db: db}
+}
+
+// Returns key for the time until the client can take the action again
+func limitKey(action, client string) string {
+	return "ratelimit:" + action + ":" + client
+}
+
+// Allow records the client taking the action unless it already took it within the cooldown.
+// If it is not allowed, the time until the client can take the action again is returned.
+// Actions without a cooldown are always allowed.
+func (l *Limiter) Allow(action, client string, cooldown time.Duration) (bool, time.Duration, error) {
+	if cooldown <= 0 {
+		return true, 0, nil
+	}
+
+	key := limitKey(action, client)
+	until := time.Now().Add(cooldown)
+	set, err := l.db.SetIfAbsent(data.NewKeyValuePair(key, strconv.FormatInt(until.UnixNano(), 10)), cooldown)
+	if err != nil || set {
+		return set, 0, err
+	}
+
+	value, err := l.db.Get(key)
+	if err != nil {
+		// The cooldown ended since trying to set it
+		return l.Allow(action, client, cooldown)
+	}
+	nanos, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return false, cooldown, nil
+	}
+	return false, time.Until(time.Unix(0, nanos)), nil
+}
+
+// Reset forgets the client taking the action, so it can take it again straight away.
+func (l *Limiter) Reset(action, client string) error {
+	return l.db.Remove(limitKey(action, client))
+}
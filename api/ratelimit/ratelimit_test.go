@@ -0,0 +1,57 @@
+package ratelimit
+
+import (
+	"github.com/alice-ws/alice/data"
+	"testing"
+	"time"
+)
+
+func TestLimiter_Allow(t *testing.T) {
+	limiter := NewLimiter(data.NewMemoryDB())
+
+	if allowed, _, err := limiter.Allow("post", "1.2.3.4", time.Minute); err != nil || !allowed {
+		t.Errorf("Allow() = %t, %v, want the first post to be allowed", allowed, err)
+	}
+	allowed, retryAfter, _ := limiter.Allow("post", "1.2.3.4", time.Minute)
+	if allowed || retryAfter <= 59*time.Second || retryAfter > time.Minute {
+		t.Errorf("Allow() = %t, %v, want the second post to wait about a minute", allowed, retryAfter)
+	}
+	if allowed, _, _ := limiter.Allow("post", "5.6.7.8", time.Minute); !allowed {
+		t.Errorf("Allow() expected other clients to be allowed")
+	}
+	if allowed, _, _ := limiter.Allow("thread", "1.2.3.4", time.Minute); !allowed {
+		t.Errorf("Allow() expected other actions to be allowed")
+	}
+}
+
+func TestLimiter_Allow_afterCooldown(t *testing.T) {
+	limiter := NewLimiter(data.NewMemoryDB())
+	_, _, _ = limiter.Allow("post", "1.2.3.4", 10*time.Millisecond)
+
+	time.Sleep(20 * time.Millisecond)
+
+	if allowed, _, _ := limiter.Allow("post", "1.2.3.4", 10*time.Millisecond); !allowed {
+		t.Errorf("Allow() expected the post to be allowed after the cooldown")
+	}
+}
+
+func TestLimiter_Allow_noCooldown(t *testing.T) {
+	limiter := NewLimiter(data.NewMemoryDB())
+
+	for i := 0; i < 3; i++ {
+		if allowed, _, _ := limiter.Allow("post", "1.2.3.4", 0); !allowed {
+			t.Errorf("Allow() expected actions without a cooldown to always be allowed")
+		}
+	}
+}
+
+func TestLimiter_Reset(t *testing.T) {
+	limiter := NewLimiter(data.NewMemoryDB())
+	_, _, _ = limiter.Allow("post", "1.2.3.4", time.Minute)
+
+	_ = limiter.Reset("post", "1.2.3.4")
+
+	if allowed, _, _ := limiter.Allow("post", "1.2.3.4", time.Minute); !allowed {
+		t.Errorf("Allow() expected the post to be allowed after a reset")
+	}
+}
@@ -4,12 +4,16 @@ import (
 	"errors"
 	"github.com/alice-ws/alice/data"
 	"github.com/go-redis/redis"
+	"time"
 )
 
 type RedisClient struct {
 	client *redis.Client
 }
 
+// Number of times an optimistic transaction is retried before giving up.
+const maxTransactionRetries = 100
+
 func (r *RedisClient) SetOrdered(kv data.KeyValue, score int) error {
 	result := r.client.ZAdd(kv.Key(), redis.Z{
 		Score:  float64(score),
@@ -31,6 +35,18 @@ func (r *RedisClient) GetAllOrderedByScore(key string) []string {
 	return strings
 }
 
+func (r *RedisClient) GetRangeByRank(key string, start, stop int64) []string {
+	strings, err := r.client.ZRevRange(key, start, stop).Result()
+	if err != nil {
+		return nil
+	}
+	return strings
+}
+
+func (r *RedisClient) CountOrdered(key string) int64 {
+	return r.client.ZCard(key).Val()
+}
+
 func (r *RedisClient) RemoveOrdered(kv data.KeyValue) error {
 	result := r.client.ZRem(kv.Key(), kv.String())
 	return result.Err()
@@ -61,6 +77,10 @@ func (r *RedisClient) Set(kv data.KeyValue) error {
 	return err
 }
 
+func (r *RedisClient) SetIfAbsent(kv data.KeyValue, expiry time.Duration) (bool, error) {
+	return r.client.SetNX(kv.Key(), kv.String(), expiry).Result()
+}
+
 func (r *RedisClient) Increment(key string) (int64, error) {
 	incr := r.client.Incr(key)
 	return incr.Val(), incr.Err()
@@ -78,3 +98,33 @@ func (r *RedisClient) Remove(key string) error {
 	err := r.client.Del(key).Err()
 	return err
 }
+
+// Update watches the key and replaces its value in a MULTI/EXEC transaction.
+// The transaction is retried if the key was modified between reading and writing.
+func (r *RedisClient) Update(key string, update func(string) (string, error)) error {
+	transaction := func(tx *redis.Tx) error {
+		current, err := tx.Get(key).Result()
+		if err != nil {
+			return errors.New("error getting key " + key + " with " + err.Error())
+		}
+
+		updated, err := update(current)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Pipelined(func(pipe redis.Pipeliner) error {
+			pipe.Set(key, updated, 0)
+			return nil
+		})
+		return err
+	}
+
+	for i := 0; i < maxTransactionRetries; i++ {
+		err := r.client.Watch(transaction, key)
+		if err != redis.TxFailedErr {
+			return err
+		}
+	}
+	return errors.New("error updating key " + key + ": too many concurrent modifications")
+}
@@ -0,0 +1,31 @@
+package redisclient
+
+import (
+	"github.com/alice-ws/alice/data"
+	"github.com/alice-ws/alice/data/dbtest"
+	"github.com/alicebob/miniredis/v2"
+	"testing"
+)
+
+func TestRedisClient_conformance(t *testing.T) {
+	s, newDB := fakeRedis(t)
+	defer s.Close()
+	dbtest.Run(t, newDB)
+}
+
+// Returns an in process fake Redis, which the caller must close, and a DB factory backed by it that empties it on every call.
+func fakeRedis(t *testing.T) (*miniredis.Miniredis, dbtest.NewDB) {
+	s, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("could not start fake redis: %v", err)
+	}
+	client, err := ConnectToRedis(s.Addr())
+	if err != nil {
+		s.Close()
+		t.Fatalf("could not connect to fake redis: %v", err)
+	}
+	return s, func() data.DB {
+		s.FlushAll()
+		return client
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/alice-ws/alice/board"
+	"github.com/alice-ws/alice/media"
+	"github.com/spf13/viper"
+	"io/ioutil"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+)
+
+// How long to wait for the lock on media held while it is stored or removed elsewhere
+const mediaLockTimeout = 10 * time.Second
+
+// Validates the uploaded file against the board's settings, strips its metadata if the board does,
+// then stores it along with a thumbnail of it, attaching both to the post.
+// Files are named by their hash so a file uploaded twice is only stored once.
+// Files that are not images, such as WebMs, are stored without a thumbnail.
+// The stored files are locked until the returned function is called, which should be once the post is stored.
+// Rejected uploads return a *media.Error giving the reason.
+func attachUpload(post board.Post, header *multipart.FileHeader, store *board.Store) (board.Post, func(), error) {
+	settings := store.Settings()
+	if !settings.AllowsFile(header.Filename) {
+		return post, nil, &media.Error{Code: media.UnsupportedType, Reason: "file " + header.Filename + " is not allowed on this board"}
+	}
+	if header.Size > settings.MaxFileSize {
+		return post, nil, &media.Error{Code: media.FileTooLarge, Reason: "file too large: " + header.Filename}
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		return post, nil, err
+	}
+	defer file.Close()
+	contents, err := ioutil.ReadAll(file)
+	if err != nil {
+		return post, nil, err
+	}
+
+	info, err := media.Validate(contents, header.Filename, media.Limits{MaxSize: settings.MaxFileSize, MaxWidth: settings.MaxWidth, MaxHeight: settings.MaxHeight})
+	if err != nil {
+		return post, nil, err
+	}
+	thumbnail, err := media.NewThumbnail(contents)
+	if err != nil && err != media.ErrNotImage {
+		return post, nil, &media.Error{Code: media.CorruptFile, Reason: "file " + header.Filename + " cannot be read: " + err.Error()}
+	}
+
+	// Bans are checked against the file as uploaded as well as stored, as boards that strip metadata store a different file
+	uploadedHash := media.Hash(contents)
+	if settings.StripMetadata {
+		contents, err = media.StripMetadata(contents)
+		if err != nil {
+			return post, nil, &media.Error{Code: media.CorruptFile, Reason: "file " + header.Filename + " cannot be read: " + err.Error()}
+		}
+	}
+	hash := media.Hash(contents)
+	if fileBans.IsBanned(uploadedHash) || fileBans.IsBanned(hash) {
+		return post, nil, &media.Error{Code: media.BannedFile, Reason: "file " + header.Filename + " is banned"}
+	}
+	if settings.RejectReposts && store.HasFile(hash) {
+		return post, nil, &media.Error{Code: media.Repost, Reason: "file " + header.Filename + " has already been posted"}
+	}
+	if thumbnail.PerceptualHash != "" {
+		ban, banned, err := imageBans.Similar(thumbnail.PerceptualHash, viper.GetInt("media.perceptual.threshold"))
+		if err != nil {
+			return post, nil, err
+		}
+		if banned {
+			return post, nil, &media.Error{Code: media.BannedImage, Reason: "image " + header.Filename + " is too similar to a banned image: " + ban.Reason}
+		}
+	}
+
+	ext := strings.ToLower(path.Ext(header.Filename))
+	URI, release, err := storeOnce(contents, hash+ext)
+	if err != nil {
+		return post, nil, err
+	}
+	post.Image = URI
+	post.Filename = header.Filename
+	post.Size = int64(len(contents))
+	post.Width = info.Width
+	post.Height = info.Height
+	post.Hash = hash
+	post.PerceptualHash = thumbnail.PerceptualHash
+
+	if len(thumbnail.Data) == 0 {
+		// Videos are stored without a thumbnail
+		return post, release, nil
+	}
+
+	URI, releaseThumbnail, err := storeOnce(thumbnail.Data, hash+"s"+thumbnail.Ext)
+	if err != nil {
+		log.Printf("Could not store thumbnail of %s: %v", header.Filename, err)
+		return post, release, nil
+	}
+	post.Thumbnail = URI
+	post.ThumbnailWidth = thumbnail.Width
+	post.ThumbnailHeight = thumbnail.Height
+	return post, func() {
+		release()
+		releaseThumbnail()
+	}, nil
+}
+
+// Stores the file with the name, locking it until the returned function is called.
+// Names are derived from the file's contents so a file uploaded twice is only stored once.
+// A file that is already stored is stored again, so it is not removed as unreferenced before the new post references it:
+// the lock keeps it from moderators removing media, and storing it again restarts its grace period before reconciliation.
+func storeOnce(file []byte, name string) (string, func(), error) {
+	URI := mediaRepo.URI(dependencyManagement.ImageGroup(), name)
+	release, err := mediaLocks.Lock(URI, mediaLockTimeout)
+	if err != nil {
+		return "", nil, err
+	}
+	URI, err = mediaRepo.Store(bytes.NewReader(file), dependencyManagement.ImageGroup(), name, int64(len(file)))
+	if err != nil {
+		release()
+		return "", nil, err
+	}
+	return URI, release, nil
+}
+
+// Responds with the code for why the upload was rejected, or as a bad request for any other error.
+func uploadRejected(err error, w http.ResponseWriter) bool {
+	rejected, ok := err.(*media.Error)
+	if !ok {
+		return badRequest(err, w)
+	}
+	log.Printf("Rejected upload (%s): %s", rejected.Code, rejected.Reason)
+	w.WriteHeader(http.StatusBadRequest)
+	_ = json.NewEncoder(w).Encode(boardResponse{Status: "FAILURE", Code: rejected.Code, Error: rejected.Reason})
+	return true
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"encoding/json"
+	"github.com/alice-ws/alice/auth"
+	"github.com/julienschmidt/httprouter"
+	"log"
+	"net/http"
+	"strings"
+)
+
+func loginHandler(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	username := r.FormValue("username")
+	token, err := authenticator.Login(username, r.FormValue("password"))
+
+	if err != nil {
+		log.Printf("Failed login for user %s", username)
+		w.WriteHeader(http.StatusUnauthorized)
+		_ = json.NewEncoder(w).Encode(userResponse{Status: "FAILURE", Username: username, Error: err.Error()})
+		return
+	}
+
+	addHeaders(w)
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(userResponse{Status: "SUCCESS", Username: username, Token: token})
+}
+
+func userHandler(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	claims, _ := auth.ClaimsFrom(r.Context())
+
+	addHeaders(w)
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(userResponse{Status: "SUCCESS", Username: claims.Username(), Role: string(claims.Role)})
+}
+
+// Wraps the handler so it is only served to requests with a valid bearer token for a user with at least the role.
+// The token's claims are attached to the request context.
+func authorised(role auth.Role, h httprouter.Handle) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		claims, err := authenticator.Validate(token)
+
+		if err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			_ = json.NewEncoder(w).Encode(userResponse{Status: "FAILURE", Error: err.Error()})
+			return
+		}
+		if !claims.Role.Includes(role) {
+			w.WriteHeader(http.StatusForbidden)
+			_ = json.NewEncoder(w).Encode(userResponse{Status: "FAILURE", Username: claims.Username(), Error: "requires role " + string(role)})
+			return
+		}
+
+		h(w, r.WithContext(auth.WithClaims(r.Context(), claims)), ps)
+	}
+}
@@ -12,7 +12,7 @@ func TestGetThreadAllWithEmptyDB(t *testing.T) {
 	e := setup(t)
 	e.GET("/thread/all").
 		Expect().
-		Status(http.StatusOK).JSON().Null()
+		Status(http.StatusOK).JSON().Object().Value("threads").Array().Empty()
 }
 
 func TestGetThreadAllWithOneThread(t *testing.T) {
@@ -23,7 +23,7 @@ func TestGetThreadAllWithOneThread(t *testing.T) {
 	e := setup(t)
 	e.GET("/thread/all").
 		Expect().
-		Status(http.StatusOK).JSON().Array().Equal(op.ExpectedThreads())
+		Status(http.StatusOK).JSON().Object().Value("threads").Array().Equal(op.ExpectedPreviews())
 }
 
 func TestGetThreadAllWithTwoThreads(t *testing.T) {
@@ -34,7 +34,7 @@ func TestGetThreadAllWithTwoThreads(t *testing.T) {
 	e := setup(t)
 	e.GET("/thread/all").
 		Expect().
-		Status(http.StatusOK).JSON().Array().Equal(op.ExpectedThreads())
+		Status(http.StatusOK).JSON().Object().Value("threads").Array().Equal(op.ExpectedPreviews())
 }
 
 func TestGetThreadAllWithTwoVariedNumberedThreads(t *testing.T) {
@@ -45,7 +45,7 @@ func TestGetThreadAllWithTwoVariedNumberedThreads(t *testing.T) {
 	e := setup(t)
 	e.GET("/thread/all").
 		Expect().
-		Status(http.StatusOK).JSON().Array().Equal(op.ExpectedThreads())
+		Status(http.StatusOK).JSON().Object().Value("threads").Array().Equal(op.ExpectedPreviews())
 }
 
 func TestGetThreadWithThreadNotFound(t *testing.T) {
@@ -19,6 +19,11 @@ import (
 
 const boardID = "/obj/"
 
+// Returns the key the API stores a thread under for the board
+func threadKey(no uint64) string {
+	return boardID + ":thread:" + strconv.FormatUint(no, 10)
+}
+
 const (
 	empty = -1
 
@@ -94,7 +99,7 @@ func (tm *Controller) Thread(threadNo ...uint64) *Controller {
 			tm.WithNo(threadNo[0])
 		}
 	case getting:
-		get := tm.redis.Get(strconv.FormatUint(threadNo[0], 10))
+		get := tm.redis.Get(threadKey(threadNo[0]))
 		if get.Err() != nil {
 			log.Fatalf("getting thread from redis error: %v", get.Err())
 		}
@@ -144,7 +149,7 @@ func (tm *Controller) ToRedis() *Controller {
 		for _, t := range tm.threads {
 			tm.redis.Set(boardID+":no", tm.currentBoardCount+1, 0)
 			tm.redis.ZAdd(boardID, redis.Z{Score: float64(t.Timestamp.UnixNano()), Member: t.No})
-			tm.redis.Set(strconv.FormatUint(t.No, 10), t.AsJSON(), 0)
+			tm.redis.Set(threadKey(t.No), t.AsJSON(), 0)
 		}
 	} else {
 		panic("no thread was set up to be added to Redis")
@@ -287,6 +292,24 @@ func (tm *Controller) ExpectedThreads() []Thread {
 	return list
 }
 
+// ExpectedPreviews returns the expected threads as shown on the board index, with their latest 5 replies.
+func (tm *Controller) ExpectedPreviews() []Preview {
+	var list []Preview
+	for _, t := range tm.ExpectedThreads() {
+		p := Preview{Thread: t, ReplyCount: len(t.Replies)}
+		for _, reply := range t.Replies {
+			if reply.Image != "" {
+				p.ImageCount++
+			}
+		}
+		if len(t.Replies) > 5 {
+			p.Replies = t.Replies[len(t.Replies)-5:]
+		}
+		list = append(list, p)
+	}
+	return list
+}
+
 func (tm *Controller) getAllThreadsAsJSON() []string {
 	var listAsJSON []string
 
@@ -18,14 +18,30 @@ type Thread struct {
 	Replies []Post `json:"replies"`
 }
 
+type Preview struct {
+	Thread
+	ReplyCount int `json:"reply_count"`
+	ImageCount int `json:"image_count"`
+}
+
 type Post struct {
 	No              uint64    `json:"no"`
 	Timestamp       time.Time `json:"timestamp"`
 	Name            string    `json:"name"`
+	Trip            string    `json:"trip"`
+	PosterID        string    `json:"poster_id,omitempty"`
 	Email           string    `json:"email"`
 	Comment         string    `json:"comment"`
 	CommentSegments []Segment `json:"comment_segments"`
 	Image           string    `json:"image"`
+	Thumbnail       string    `json:"thumbnail"`
+	ThumbnailWidth  int       `json:"thumbnail_width"`
+	ThumbnailHeight int       `json:"thumbnail_height"`
+	Width           int       `json:"width"`
+	Height          int       `json:"height"`
+	Size            int64     `json:"size"`
+	Hash            string    `json:"hash"`
+	PerceptualHash  string    `json:"perceptual_hash"`
 	Filename        string    `json:"filename"`
 	Meta            string    `json:"meta"`
 	QuotedBy        []uint64  `json:"quoted_by"`
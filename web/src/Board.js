@@ -10,25 +10,29 @@ class Board extends React.Component {
       name: this.props.name,
       apiUrl: this.props.apiUrl,
       imageContext: this.props.imageContext,
-      threads: []
+      threads: [],
+      page: 1,
+      totalPages: 1
     }
   }
 
-  getAllThreads() {
-    fetch(this.state.apiUrl + '/thread/all/')
+  getAllThreads(page) {
+    fetch(this.state.apiUrl + '/thread/all?page=' + page)
     .then((response) => {
       return response.json()
     })
     .then((json) => {
       console.log(json);
       this.setState({
-        threads: json
+        threads: json.threads,
+        page: json.page,
+        totalPages: json.total_pages
       })
     }).catch(console.log);
   }
 
   componentDidMount() {
-    this.getAllThreads();
+    this.getAllThreads(1);
   }
 
   render() {
@@ -37,6 +41,7 @@ class Board extends React.Component {
           <h2>/{this.state.name}/</h2>
           <NewPostForm apiUrl={this.state.apiUrl}/>
           {this.allThreads()}
+          {this.pages()}
         </div>
     );
   }
@@ -45,15 +50,30 @@ class Board extends React.Component {
     if (this.state.threads == null) {
       return (<div> . . . </div>)
     }
-    let threads = this.state.threads.slice(0, 10);
-    return threads.map((thread) => {
+    return this.state.threads.map((thread) => {
       return (<Thread key={thread.post.no} board={this.state.name}
                       apiUrl={this.state.apiUrl}
-                      imageContext={this.state.imageContext} thread={thread}
-                      limit='5'/>);
+                      imageContext={this.state.imageContext} thread={thread}/>);
     })
   }
 
+  showPage(page) {
+    this.getAllThreads(page);
+    window.scrollTo(0, 0);
+  }
+
+  pages() {
+    let pages = [];
+    for (let page = 1; page <= this.state.totalPages; page++) {
+      if (page === this.state.page) {
+        pages.push(<li key={page}>[{page}]</li>);
+      } else {
+        pages.push(<li key={page}>[<span className="clickable" onClick={() => this.showPage(page)}>{page}</span>]</li>);
+      }
+    }
+    return (<ul className="menu pages">{pages}</ul>);
+  }
+
 }
 
 export default Board
\ No newline at end of file
@@ -42,10 +42,20 @@ class Thread extends React.Component {
     }
 
     displayImage(post) {
+        // Posts made before thumbnails were generated only have the original image
+        let image = post.thumbnail ? post.thumbnail : post.image;
         if (this.state.imageContext.startsWith("http")) {
-            return this.state.imageContext + "/" + post.image
+            return this.state.imageContext + "/" + image
         }
-        return process.env.PUBLIC_URL + this.state.imageContext + post.image;
+        return process.env.PUBLIC_URL + this.state.imageContext + image;
+    }
+
+    // Thumbnails are shown at their own size so the page does not move as they load
+    imageSize(post) {
+        if (!post.thumbnail) {
+            return {};
+        }
+        return {width: post.thumbnail_width, height: post.thumbnail_height};
     }
 
     displayThread(thread) {
@@ -54,21 +64,30 @@ class Thread extends React.Component {
                 <hr/>
                 <div className="thread">
                     <span className="image"><img alt={thread.post.filename}
-                                                 src={this.displayImage(thread.post)}/></span><span
+                                                 src={this.displayImage(thread.post)} {...this.imageSize(thread.post)}/></span><span
                     className="threadHeader">{thread.subject} <span
-                    className="postName">{thread.post.name}</span> {thread.post.timestamp} No. <Link
+                    className="postName">{thread.post.name}</span><span
+                    className="postTrip">{thread.post.trip}</span>{this.posterID(thread.post)} {thread.post.timestamp} No. <Link
                     to={"/" + this.state.board + "/res/" + thread.post.no}>{thread.post.no}</Link> <span
                     className="quotedBy">{this.quotedBy(thread.post, thread)}</span></span>
 
                     <div><span className="content">{this.displayComment(thread.post)}</span></div>
                 </div>
+                {this.omittedReplies(thread)}
                 <div className="replies">
-                    {this.displayReplies(thread, 5)}
+                    {this.displayReplies(thread)}
                 </div>
             </div>
         );
     }
 
+    omittedReplies(thread) {
+        if (thread.reply_count === undefined || thread.reply_count <= thread.replies.length) {
+            return <span/>
+        }
+        return <span className="omitted">{thread.reply_count - thread.replies.length} replies omitted.</span>
+    }
+
     displayReplies(thread) {
         if (this.state.limit !== null) {
             thread.replies = thread.replies.slice(-this.state.limit)
@@ -84,7 +103,8 @@ class Thread extends React.Component {
         return <div key={post.no} className="post">
             {this.optionalImage(post)}
             <span className="postHeader"><span
-                className="postName">{post.name}</span> {post.timestamp} No. {post.no} <span
+                className="postName">{post.name}</span><span
+                className="postTrip">{post.trip}</span>{this.posterID(post)} {post.timestamp} No. {post.no} <span
                 className="quotedBy">{this.quotedBy(post, thread, hover)}</span></span>
             <div><span className="content">{this.displayComment(post)}</span></div>
         </div>;
@@ -162,12 +182,18 @@ class Thread extends React.Component {
     optionalImage(post) {
         if (post.image != null && post.image !== "") {
             return <span className="image"><img src={this.displayImage(post)}
-                                                alt={post.filename}/></span>
+                                                alt={post.filename} {...this.imageSize(post)}/></span>
         } else {
             return <span className="noImage"/>
         }
     }
 
+    posterID(post) {
+        if (post.poster_id) {
+            return <span className="posterID"> (ID: {post.poster_id})</span>
+        }
+    }
+
     render() {
         if (this.state.thread === undefined || this.state.thread === null || this.state.status === "FAILURE") {
             return (<div>. . .</div>)